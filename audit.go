@@ -0,0 +1,185 @@
+package gitup
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/user"
+	"sync"
+	"time"
+)
+
+// Audited events.
+const (
+	AuditCheck    = "check"
+	AuditConfirm  = "confirm"
+	AuditUpdate   = "update"
+	AuditRollback = "rollback"
+	AuditHook     = "hook"
+)
+
+// Outcomes of the audited events.
+const (
+	OutcomeOK        = "ok"
+	OutcomeFailed    = "failed"
+	OutcomeAvailable = "available"
+	OutcomeNoUpdate  = "no-update"
+	OutcomeAccepted  = "accepted"
+	OutcomeDeclined  = "declined"
+	OutcomeDeferred  = "deferred"
+)
+
+// ActorAuto is the actor of the checks, the hooks and the automatic updates, run by gitup itself.
+const ActorAuto = "auto"
+
+// AuditRecord is an entry of the audit log.
+type AuditRecord struct {
+	Time    time.Time `json:"time"`
+	Repo    string    `json:"repo"`
+	Path    string    `json:"path"`
+	Event   string    `json:"event"`
+	Old     string    `json:"old,omitempty"`
+	New     string    `json:"new,omitempty"`
+	Actor   string    `json:"actor"`
+	Outcome string    `json:"outcome"`
+	Hook    string    `json:"hook,omitempty"`
+	Command string    `json:"command,omitempty"`
+	Err     string    `json:"error,omitempty"`
+}
+
+// AuditFilter selects the records of the audit log. Its zero value selects all of them.
+type AuditFilter struct {
+	Repo  string
+	Event string
+	Since time.Time
+}
+
+// AuditLog appends the records as JSON Lines to a file, one record by line.
+type AuditLog struct {
+	mu   sync.Mutex
+	path string
+}
+
+// currentUser returns the name of the user running gitup, the actor of the manual actions.
+var currentUser = func() string {
+	if u, err := user.Current(); err == nil {
+		return u.Username
+	}
+	return os.Getenv("USER")
+}
+
+// NewAuditLog returns an audit log writing in the file at this path.
+func NewAuditLog(path string) *AuditLog {
+	return &AuditLog{path: path}
+}
+
+// Record appends the record to the file, created if necessary.
+func (l *AuditLog) Record(rec AuditRecord) error {
+	if l == nil {
+		return nil
+	}
+	buf, err := json.Marshal(rec)
+	if err != nil {
+		return err
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	f, err := os.OpenFile(l.path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+	if _, err = f.Write(append(buf, '\n')); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// Records returns in order the records matching the filter. A missing file has no record.
+func (l *AuditLog) Records(f AuditFilter) ([]AuditRecord, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	file, err := os.Open(l.path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	var recs []AuditRecord
+	sc := bufio.NewScanner(file)
+	for n := 1; sc.Scan(); n++ {
+		if len(sc.Bytes()) == 0 {
+			continue
+		}
+		var rec AuditRecord
+		if err = json.Unmarshal(sc.Bytes(), &rec); err != nil {
+			return nil, fmt.Errorf("%s:%d: %v", l.path, n, err)
+		}
+		if f.matches(rec) {
+			recs = append(recs, rec)
+		}
+	}
+	return recs, sc.Err()
+}
+
+// matches returns true if the record is selected by the filter.
+func (f AuditFilter) matches(rec AuditRecord) bool {
+	return (f.Repo == "" || f.Repo == rec.Repo) &&
+		(f.Event == "" || f.Event == rec.Event) &&
+		!rec.Time.Before(f.Since)
+}
+
+// SetAuditLog defines the audit log recording the checks, the confirmations, the updates,
+// the rollbacks and the hooks of the repository.
+func (r *Repo) SetAuditLog(l *AuditLog) {
+	r.audit = l
+}
+
+// record appends the event to the audit log of the repository.
+// It deliberately ignores the errors of the audit log, which never blocks the updates.
+func (r *Repo) record(rec AuditRecord) {
+	if r.audit == nil {
+		return
+	}
+	rec.Time, rec.Repo, rec.Path = time.Now(), r.displayName(), r.path
+	if rec.Actor == "" {
+		rec.Actor = ActorAuto
+	}
+	r.audit.Record(rec)
+}
+
+// recordCheck appends the result of a check to the audit log.
+func (r *Repo) recordCheck(ok bool, err error) {
+	res := OutcomeNoUpdate
+	if ok {
+		res = OutcomeAvailable
+	}
+	rec := AuditRecord{Event: AuditCheck, Old: r.local, New: r.remote}
+	rec.Outcome, rec.Err = outcome(res, err)
+	r.record(rec)
+}
+
+// actor returns the actor of an update: the one forcing it, the current user if it is manual, auto otherwise.
+func (r *Repo) actor(forcedBy string) string {
+	switch {
+	case forcedBy != "":
+		return forcedBy
+	case r.upStrategy != Auto:
+		return currentUser()
+	}
+	return ActorAuto
+}
+
+// outcome returns the outcome failed with the error, the given one otherwise.
+func outcome(ok string, err error) (string, string) {
+	if err != nil {
+		return OutcomeFailed, err.Error()
+	}
+	return ok, ""
+}
@@ -0,0 +1,119 @@
+package gitup
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// TestAuditLog tests the records of a manual update refused then accepted, a rollback and a failing hook.
+func TestAuditLog(t *testing.T) {
+	dir, err := ioutil.TempDir(os.TempDir(), "gitup")
+	if err != nil {
+		t.Fatalf("Unable to create directory, received: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	user := currentUser
+	currentUser = func() string { return "alice" }
+	defer func() { currentUser, stdin = user, os.Stdin }()
+
+	l := NewAuditLog(filepath.Join(dir, "audit.jsonl"))
+	git := &FakeStepFlow{FakeGitFlow: FakeGitFlow{localTag: "v1.0.0", remoteTag: "v1.1.0"}}
+	r := &Repo{git: git, name: "app"}
+	r.SetAuditLog(l)
+	r.AddHook(AfterUpdate, Command("true"))
+	r.AddHook(OnError, HookFunc(func(HookEvent) error { return errors.New(errMsgFake) }))
+	s := UpdateStrategy{until: [4]uint8{Manual}}
+
+	for _, answer := range []string{"n\n", "y\n"} {
+		if stdin, err = fakeStdin(answer); err != nil {
+			t.Fatalf("Unable to mock stdin, received: %v", err)
+		}
+		if err = r.Update(s); err != nil {
+			t.Fatalf("Expected no error, received: %v", err)
+		}
+		stdin.Close()
+		os.Remove(stdin.Name())
+	}
+	if err = r.Rollback(); err != nil {
+		t.Fatalf("Expected no error, received: %v", err)
+	}
+	r.git = &FakeStepFlow{FakeGitFlow: FakeGitFlow{localTag: "v1.0.0", remoteError: true}}
+	r.refresh()
+	r.InDemand(s)
+
+	recs, err := l.Records(AuditFilter{})
+	if err != nil {
+		t.Fatalf("Expected no error, received: %v", err)
+	}
+	var got []string
+	for _, rec := range recs {
+		if rec.Repo != "app" || rec.Time.IsZero() {
+			t.Errorf("Expected repository and time, received: %#v", rec)
+		}
+		got = append(got, strings.Join([]string{rec.Event, rec.Old, rec.New, rec.Actor, rec.Outcome, rec.Hook, rec.Err}, "|"))
+	}
+	expected := []string{
+		"check|v1.0.0|v1.1.0|auto|available||",
+		"confirm|v1.0.0|v1.1.0|alice|declined||",
+		"check|v1.0.0|v1.1.0|auto|available||",
+		"confirm|v1.0.0|v1.1.0|alice|accepted||",
+		"hook|v1.0.0|v1.1.0|auto|ok|after_update|",
+		"update|v1.0.0|v1.1.0|alice|ok||",
+		"rollback|v1.1.0|v1.0.0|alice|ok||",
+		"check|v1.0.0||auto|failed||" + errMsgFake,
+		"hook|v1.0.0||auto|failed|on_error|" + errMsgFake,
+	}
+	if strings.Join(got, "\n") != strings.Join(expected, "\n") {
+		t.Errorf("Expected records:\n%s\nreceived:\n%s", strings.Join(expected, "\n"), strings.Join(got, "\n"))
+	}
+	// Filters
+	if recs, err = l.Records(AuditFilter{Event: AuditUpdate}); err != nil || len(recs) != 1 || recs[0].Command != "" {
+		t.Errorf("Expected one update, received: %v, %v", recs, err)
+	}
+	if recs, err = l.Records(AuditFilter{Repo: "api"}); err != nil || len(recs) != 0 {
+		t.Errorf("Expected no record for another repository, received: %v, %v", recs, err)
+	}
+	if recs, err = l.Records(AuditFilter{Since: time.Now().Add(time.Hour)}); err != nil || len(recs) != 0 {
+		t.Errorf("Expected no record in the future, received: %v, %v", recs, err)
+	}
+	if recs, err = NewAuditLog(filepath.Join(dir, "missing")).Records(AuditFilter{}); err != nil || len(recs) != 0 {
+		t.Errorf("Expected no record without file, received: %v, %v", recs, err)
+	}
+}
+
+// TestManager_ForceUpdateAs tests that the actions requested by a caller are recorded with its identity.
+func TestManager_ForceUpdateAs(t *testing.T) {
+	dir, err := ioutil.TempDir(os.TempDir(), "gitup")
+	if err != nil {
+		t.Fatalf("Unable to create directory, received: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	l := NewAuditLog(filepath.Join(dir, "audit.jsonl"))
+	r := &Repo{git: &FakeStepFlow{FakeGitFlow: FakeGitFlow{localTag: "v1.0.0", remoteTag: "v1.1.0"}}}
+	r.SetAuditLog(l)
+	m := NewManager()
+	m.Add("app", r, UpdateStrategy{until: [4]uint8{Manual}})
+	if err = m.ForceUpdateAs("app", "api:10.0.0.1"); err != nil {
+		t.Fatalf("Expected no error, received: %v", err)
+	}
+	if err = m.RollbackAs("app", "api:10.0.0.2"); err != nil {
+		t.Fatalf("Expected no error, received: %v", err)
+	}
+	recs, err := l.Records(AuditFilter{})
+	if err != nil || len(recs) != 3 {
+		t.Fatalf("Expected a check, an update and a rollback, received: %v, %v", recs, err)
+	}
+	if recs[1].Event != AuditUpdate || recs[1].Actor != "api:10.0.0.1" {
+		t.Errorf("Expected the update by the caller, received: %#v", recs[1])
+	}
+	if recs[2].Event != AuditRollback || recs[2].Actor != "api:10.0.0.2" {
+		t.Errorf("Expected the rollback by the caller, received: %#v", recs[2])
+	}
+}
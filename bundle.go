@@ -0,0 +1,120 @@
+package gitup
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/rvflash/gitup/internal/gitflow"
+)
+
+// Bundle settings.
+const (
+	errMsgBundle         = "bundles require a Git repository"
+	errMsgBundleChecksum = "bundle does not match the expected checksum"
+	errMsgBundlePin      = "bundle does not match the tags pinned by its manifest"
+	manifestExt          = ".json"
+)
+
+// BundleManifest describes a bundle exported for an air-gapped host: its checksum
+// and the tags it contains, pinned to the name of their object.
+type BundleManifest struct {
+	Repo    string            `json:"repo"`
+	Basis   string            `json:"basis,omitempty"`
+	Tags    map[string]string `json:"tags"`
+	SHA256  string            `json:"sha256"`
+	Created time.Time         `json:"created"`
+}
+
+// ExportBundle writes in the file a Git bundle of the tags and their objects not reachable from the basis,
+// like the version of the air-gapped host, and its manifest in the same file with the extension .json.
+func (r *Repo) ExportBundle(file, basis string) (m BundleManifest, err error) {
+	g, ok := r.git.(*gitflow.Repo)
+	if !ok {
+		return m, errors.New(errMsgBundle)
+	}
+	m = BundleManifest{Repo: r.displayName(), Basis: basis, Created: time.Now()}
+	if m.Tags, err = g.CreateBundle(file, basis); err != nil {
+		return
+	}
+	if m.SHA256, err = checksum(file); err != nil {
+		return
+	}
+	var buf []byte
+	if buf, err = json.MarshalIndent(m, "", "  "); err != nil {
+		return
+	}
+	err = ioutil.WriteFile(file+manifestExt, buf, 0644)
+	return
+}
+
+// ImportBundle verifies the bundle against the checksum, received through another channel than the bundle,
+// and the signature of each of its tags against the keys trusted by the local Git configuration.
+// Then it uses the bundle instead of the remote to get the latest version. The update itself follows
+// the strategy, like with the remote. The tags of the bundle never replace the existing ones.
+func (r *Repo) ImportBundle(file, sum string) error {
+	g, ok := r.git.(*gitflow.Repo)
+	if !ok {
+		return errors.New(errMsgBundle)
+	}
+	m, err := ReadBundleManifest(file + manifestExt)
+	if err != nil {
+		return err
+	}
+	actual, err := checksum(file)
+	if err != nil {
+		return err
+	}
+	// The manifest travels with the bundle, only the checksum given by the user is trusted.
+	if sum = strings.ToLower(strings.TrimSpace(sum)); sum == "" || sum != actual || m.SHA256 != actual {
+		return errors.New(errMsgBundleChecksum)
+	}
+	tags, err := g.VerifyBundleTags(file)
+	if err != nil {
+		return err
+	}
+	if len(tags) != len(m.Tags) {
+		return errors.New(errMsgBundlePin)
+	}
+	for tag, obj := range tags {
+		if m.Tags[tag] != obj {
+			return fmt.Errorf("%s: %s", errMsgBundlePin, tag)
+		}
+	}
+	if err = g.SetBundle(file); err != nil {
+		return err
+	}
+	r.refresh()
+	return nil
+}
+
+// ReadBundleManifest returns the manifest read from the JSON file.
+func ReadBundleManifest(file string) (m BundleManifest, err error) {
+	var buf []byte
+	if buf, err = ioutil.ReadFile(file); err == nil {
+		err = json.Unmarshal(buf, &m)
+	}
+	return
+}
+
+// checksum returns the hexadecimal SHA256 checksum of the file.
+func checksum(file string) (string, error) {
+	f, err := os.Open(file)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	h := sha256.New()
+	if _, err = io.Copy(h, f); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
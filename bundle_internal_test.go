@@ -0,0 +1,137 @@
+package gitup
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// runGit runs the git command in the directory, with the environment variables.
+func runGit(t *testing.T, dir string, env []string, args ...string) {
+	args = append([]string{"-C", dir, "-c", "user.name=gitup", "-c", "user.email=gitup@example.com"}, args...)
+	cmd := exec.Command("git", args...)
+	cmd.Env = append(os.Environ(), env...)
+	if out, err := cmd.CombinedOutput(); err != nil {
+		t.Fatalf("Unable to run git %v, received: %v, %s", args, err, out)
+	}
+}
+
+// newBundleRepos returns an online clone with the tags v1.0.0 and v1.1.0, signed by the SSH key
+// dir/key, and an air-gapped one, without remote, on the tag v1.0.0.
+func newBundleRepos(t *testing.T) (dir string) {
+	var err error
+	if dir, err = ioutil.TempDir(os.TempDir(), "gitup"); err != nil {
+		t.Fatalf("Unable to create directory, received: %v", err)
+	}
+	key := filepath.Join(dir, "key")
+	if out, err := exec.Command("ssh-keygen", "-q", "-t", "ed25519", "-N", "", "-C", "gitup", "-f", key).CombinedOutput(); err != nil {
+		t.Fatalf("Unable to create the signing key, received: %v, %s", err, out)
+	}
+	remote := filepath.Join(dir, "remote")
+	os.Mkdir(remote, 0755)
+	runGit(t, remote, nil, "init", "-q")
+	// Commits on distinct dates to order them.
+	runGit(t, remote, []string{"GIT_COMMITTER_DATE=2017-06-01T10:00:00Z"}, "commit", "-q", "--allow-empty", "-m", "v1.0.0")
+	runGit(t, remote, nil, "tag", "-a", "v1.0.0", "-m", "v1.0.0")
+	runGit(t, dir, nil, "clone", "-q", remote, "offline")
+	runGit(t, filepath.Join(dir, "offline"), nil, "remote", "remove", "origin")
+	runGit(t, filepath.Join(dir, "offline"), nil, "checkout", "-q", "tags/v1.0.0")
+	runGit(t, remote, []string{"GIT_COMMITTER_DATE=2017-06-02T10:00:00Z"}, "commit", "-q", "--allow-empty", "-m", "v1.1.0")
+	runGit(t, remote, nil, "-c", "gpg.format=ssh", "-c", "user.signingkey="+key, "tag", "-s", "v1.1.0", "-m", "v1.1.0")
+	runGit(t, dir, nil, "clone", "-q", remote, "online")
+	return
+}
+
+// TestRepo_ImportBundle tests the update of an air-gapped repository with a bundle.
+func TestRepo_ImportBundle(t *testing.T) {
+	dir := newBundleRepos(t)
+	defer os.RemoveAll(dir)
+
+	online, err := NewRepo(filepath.Join(dir, "online"))
+	if err != nil {
+		t.Fatalf("Expected no error, received: %v", err)
+	}
+	file := filepath.Join(dir, "app.bundle")
+	m, err := online.ExportBundle(file, "v1.0.0")
+	if err != nil {
+		t.Fatalf("Expected no error on export, received: %v", err)
+	}
+	if len(m.Tags) != 1 || m.Tags["v1.1.0"] == "" || m.SHA256 == "" || m.Basis != "v1.0.0" {
+		t.Fatalf("Expected the manifest of v1.1.0 since v1.0.0, received: %v", m)
+	}
+	offline, err := NewRepo(filepath.Join(dir, "offline"))
+	if err != nil {
+		t.Fatalf("Expected no error, received: %v", err)
+	}
+	// Without the expected checksum, the bundle is refused, even if it matches its manifest.
+	sum := m.SHA256
+	for _, s := range []string{"", "e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855"} {
+		if err = offline.ImportBundle(file, s); err == nil || err.Error() != errMsgBundleChecksum {
+			t.Errorf("Expected error with the checksum %q, received: %v", s, err)
+		}
+	}
+	if err = offline.ImportBundle(filepath.Join(dir, "unknown.bundle"), sum); err == nil {
+		t.Error("Expected error without bundle")
+	}
+	// Without trusting the key of the signature, the tags are refused.
+	if err = offline.ImportBundle(file, sum); err == nil || !strings.HasSuffix(err.Error(), ": v1.1.0") {
+		t.Errorf("Expected error with a tag signed by an untrusted key, received: %v", err)
+	}
+	pub, err := ioutil.ReadFile(filepath.Join(dir, "key.pub"))
+	if err != nil {
+		t.Fatalf("Unable to read the public key, received: %v", err)
+	}
+	signers := filepath.Join(dir, "allowed_signers")
+	if err = ioutil.WriteFile(signers, []byte("gitup@example.com namespaces=\"git\" "+string(pub)), 0644); err != nil {
+		t.Fatalf("Unable to write the allowed signers, received: %v", err)
+	}
+	runGit(t, filepath.Join(dir, "offline"), nil, "config", "gpg.ssh.allowedSignersFile", signers)
+	// Any change of the pins is refused.
+	pin := m.Tags["v1.1.0"]
+	m.Tags["v1.1.0"] = "9b7f1bbc8d82ef98bbb15e86f3ccb704ec35720a"
+	writeManifest(t, file, m)
+	if err = offline.ImportBundle(file, sum); err == nil || err.Error() != errMsgBundlePin+": v1.1.0" {
+		t.Errorf("Expected error with a tag pinned on another object, received: %v", err)
+	}
+	// Then the genuine one is applied with the strategy.
+	m.Tags["v1.1.0"] = pin
+	writeManifest(t, file, m)
+	if err = offline.ImportBundle(file, sum); err != nil {
+		t.Fatalf("Expected no error on import, received: %v", err)
+	}
+	if err = offline.Update(UpdateStrategy{until: [4]uint8{Auto, Auto, Auto, Auto}}); err != nil {
+		t.Fatalf("Expected no error on update, received: %v", err)
+	}
+	if tag, _ := offline.git.LocalTag(); tag != "v1.1.0" {
+		t.Errorf("Expected v1.1.0 after the import, received: %v", tag)
+	}
+}
+
+// TestRepo_ExportBundle tests the export without Git repository.
+func TestRepo_ExportBundle(t *testing.T) {
+	r, err := NewRepoFrom("", FakeGitFlow{}, FakeGitFlow{})
+	if err != nil {
+		t.Fatalf("Expected no error, received: %v", err)
+	}
+	if _, err = r.ExportBundle("app.bundle", ""); err == nil || err.Error() != errMsgBundle {
+		t.Errorf("Expected error %q, received: %v", errMsgBundle, err)
+	}
+	if err = r.ImportBundle("app.bundle", ""); err == nil || err.Error() != errMsgBundle {
+		t.Errorf("Expected error %q, received: %v", errMsgBundle, err)
+	}
+}
+
+// writeManifest overwrites the manifest of the bundle.
+func writeManifest(t *testing.T, file string, m BundleManifest) {
+	buf, err := json.Marshal(m)
+	if err != nil {
+		t.Fatalf("Unable to encode the manifest, received: %v", err)
+	}
+	if err = ioutil.WriteFile(file+manifestExt, buf, 0644); err != nil {
+		t.Fatalf("Unable to write the manifest, received: %v", err)
+	}
+}
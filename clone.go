@@ -0,0 +1,133 @@
+package gitup
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/rvflash/gitup/internal/gitflow"
+	"github.com/rvflash/gitup/internal/semver"
+)
+
+const errMsgNoVersion = "no version to check out"
+
+// Enable testing by mocking the clone.
+var gitClone = gitflow.Clone
+
+// CloneOptions represents how to clone a missing repository and the version to check out.
+type CloneOptions struct {
+	// Depth limits the history to this number of commits, the complete history if zero.
+	Depth int
+	// Filter omits objects until they are needed, like "blob:none" for a partial clone.
+	Filter string
+	// Version is the version from which the strategy selects the one to check out.
+	// Empty, the latest version without pre-release is checked out.
+	Version string
+	// Logger receives the logs of the clone, then of the repository.
+	Logger Logger
+}
+
+// Clone returns the Git repository of the path, cloned from the remote if it is missing.
+// Once cloned, it checks out the latest version automatically reached by the strategy from
+// the version of the options. An existing repository is returned as is, a failed clone is removed,
+// except the directory of the path if it already existed.
+func Clone(rawURL, path string, s UpdateStrategy, o CloneOptions) (*Repo, error) {
+	if _, err := os.Stat(filepath.Join(strings.TrimSpace(path), ".git")); err == nil {
+		return NewRepo(path)
+	}
+	created := missingDir(strings.TrimSpace(path))
+	git, err := gitClone(rawURL, path, gitflow.CloneOptions{Depth: o.Depth, Filter: o.Filter, Logger: o.Logger})
+	if err != nil {
+		return nil, err
+	}
+	r := &Repo{git: git, path: strings.TrimSpace(path), logger: o.Logger}
+	tag, err := r.initialTag(s, strings.TrimSpace(o.Version))
+	if err == nil {
+		err = r.checkout(tag)
+	}
+	if err != nil {
+		// Without version checked out, the next run has to clone it again.
+		removeClone(r.path, created)
+		return nil, err
+	}
+	return r, nil
+}
+
+// missingDir returns the first directory of the path that does not exist yet,
+// created by the clone with its children, or empty if the path exists.
+func missingDir(path string) string {
+	if _, err := os.Lstat(path); err == nil {
+		return ""
+	}
+	dir := filepath.Clean(path)
+	for {
+		parent := filepath.Dir(dir)
+		if _, err := os.Lstat(parent); err == nil || parent == dir {
+			return dir
+		}
+		dir = parent
+	}
+}
+
+// removeClone removes the directories created by the clone. If the path already existed,
+// like a mount point, only its content is removed: Git only clones in an empty directory.
+func removeClone(path, created string) {
+	if created != "" {
+		os.RemoveAll(created)
+		return
+	}
+	names, err := ioutil.ReadDir(path)
+	if err != nil {
+		return
+	}
+	for _, fi := range names {
+		os.RemoveAll(filepath.Join(path, fi.Name()))
+	}
+}
+
+// initialTag returns the version to check out after the clone: the latest one automatically reached
+// by the strategy from the base version, or without base, the latest one without pre-release.
+func (r *Repo) initialTag(s UpdateStrategy, base string) (tag string, err error) {
+	// Fetches the tags beyond the depth of a shallow clone.
+	if _, err = r.versions().LastTag(); err != nil {
+		return
+	}
+	var tags []string
+	if tags, err = r.versions().Tags(); err != nil {
+		return
+	}
+	var bv semver.Version
+	if base != "" {
+		if bv, err = semver.Parse(base); err != nil {
+			return
+		}
+	}
+	for _, t := range semver.Sort(tags) {
+		v, _ := semver.Parse(t)
+		switch {
+		case base == "":
+			if v.PreRelease != "" {
+				continue
+			}
+		case t != base:
+			if !bv.Less(v) {
+				continue
+			}
+			diff, _ := semver.Compare(base, t)
+			var action uint8
+			if action, err = r.eligible(s, diff, t); err != nil {
+				return
+			}
+			if action != Auto {
+				continue
+			}
+		}
+		tag = t
+	}
+	if tag == "" {
+		err = errors.New(errMsgNoVersion)
+	}
+	return
+}
@@ -0,0 +1,117 @@
+package gitup
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+)
+
+// newRemote returns a Git repository with the tags, committed on distinct dates to order them.
+func newRemote(t *testing.T, tags ...string) (dir string) {
+	var err error
+	if dir, err = ioutil.TempDir(os.TempDir(), "gitup"); err != nil {
+		t.Fatalf("Unable to create directory, received: %v", err)
+	}
+	runGit(t, dir, nil, "init", "-q")
+	for i, tag := range tags {
+		date := []string{fmt.Sprintf("GIT_COMMITTER_DATE=2017-06-%02dT10:00:00Z", i+1)}
+		runGit(t, dir, date, "commit", "-q", "--allow-empty", "-m", tag)
+		runGit(t, dir, nil, "tag", tag)
+	}
+	return
+}
+
+var cloneTests = []struct {
+	strategy UpdateStrategy // input
+	opts     CloneOptions   // input
+	tag      string         // expected result
+}{
+	{UpdateStrategy{}, CloneOptions{}, "v2.0.0"},
+	{UpdateStrategy{}, CloneOptions{Depth: 1}, "v2.0.0"},
+	{UpdateStrategy{}, CloneOptions{Filter: "blob:none"}, "v2.0.0"},
+	{UpdateStrategy{}, CloneOptions{Version: "v1.0.0"}, "v1.0.0"},
+	{UpdateStrategy{until: [4]uint8{Noop, Noop, Auto}}, CloneOptions{Version: "v1.0.0"}, "v1.0.1"},
+	{UpdateStrategy{until: [4]uint8{Noop, Manual, Auto}}, CloneOptions{Version: "v1.0.0"}, "v1.0.1"},
+	{UpdateStrategy{until: [4]uint8{Noop, Auto}}, CloneOptions{Version: "v1.0.0"}, "v1.1.0"},
+	{UpdateStrategy{until: [4]uint8{Auto, Auto, Auto, Auto}}, CloneOptions{Version: "v1.0.0", Depth: 1}, "v2.1.0-rc.1"},
+	{UpdateStrategy{}, CloneOptions{Version: "v3.0.0"}, ""},
+	{UpdateStrategy{}, CloneOptions{Version: "v3"}, ""},
+}
+
+// TestClone tests the clone of a missing repository on the version selected by the strategy.
+func TestClone(t *testing.T) {
+	remote := newRemote(t, "v1.0.0", "v1.0.1", "v1.1.0", "v2.0.0", "v2.1.0-rc.1")
+	defer os.RemoveAll(remote)
+
+	for i, tt := range cloneTests {
+		path := filepath.Join(remote, ".clones", strconv.Itoa(i))
+		r, err := Clone("file://"+remote, path, tt.strategy, tt.opts)
+		if tt.tag == "" {
+			if err == nil {
+				t.Errorf("%d. Expected error, received none", i)
+			} else if _, err = os.Stat(path); !os.IsNotExist(err) {
+				t.Errorf("%d. Expected the clone to be removed on error, received: %v", i, err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%d. Expected no error, received: %v", i, err)
+			continue
+		}
+		if tag, _ := r.git.LocalTag(); tag != tt.tag {
+			t.Errorf("%d. Expected %q, received: %q", i, tt.tag, tag)
+		}
+	}
+}
+
+// TestClone_Existing tests that an existing repository is returned as is.
+func TestClone_Existing(t *testing.T) {
+	remote := newRemote(t, "v1.0.0", "v1.1.0")
+	defer os.RemoveAll(remote)
+
+	path := filepath.Join(remote, ".clones", "app")
+	if _, err := Clone("file://"+remote, path, UpdateStrategy{}, CloneOptions{Version: "v1.0.0"}); err != nil {
+		t.Fatalf("Expected no error, received: %v", err)
+	}
+	r, err := Clone("file://"+remote, path, UpdateStrategy{}, CloneOptions{})
+	if err != nil {
+		t.Fatalf("Expected no error on the existing clone, received: %v", err)
+	}
+	if tag, _ := r.git.LocalTag(); tag != "v1.0.0" {
+		t.Errorf("Expected the existing clone to stay on v1.0.0, received: %q", tag)
+	}
+	if _, err = Clone("", filepath.Join(remote, ".clones", "none"), UpdateStrategy{}, CloneOptions{}); err == nil {
+		t.Error("Expected error without remote")
+	}
+}
+
+// TestClone_EmptyDir tests that a failed clone in an existing directory, like a mount point, keeps it.
+func TestClone_EmptyDir(t *testing.T) {
+	remote := newRemote(t, "v1.0.0")
+	defer os.RemoveAll(remote)
+
+	path := filepath.Join(remote, ".clones", "app")
+	if err := os.MkdirAll(path, 0755); err != nil {
+		t.Fatalf("Unable to create directory, received: %v", err)
+	}
+	if _, err := Clone("file://"+remote, path, UpdateStrategy{}, CloneOptions{Version: "v3.0.0"}); err == nil {
+		t.Fatal("Expected error with an unknown version")
+	}
+	if names, err := ioutil.ReadDir(path); err != nil || len(names) != 0 {
+		t.Errorf("Expected the directory to be kept empty, received: %v, %v", names, err)
+	}
+	// Only the missing directories are removed.
+	path = filepath.Join(remote, ".clones", "sub", "app")
+	if _, err := Clone("file://"+remote, path, UpdateStrategy{}, CloneOptions{Version: "v3.0.0"}); err == nil {
+		t.Fatal("Expected error with an unknown version")
+	}
+	if _, err := os.Stat(filepath.Join(remote, ".clones", "sub")); !os.IsNotExist(err) {
+		t.Errorf("Expected the created directories to be removed, received: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(remote, ".clones")); err != nil {
+		t.Errorf("Expected the existing directory to be kept, received: %v", err)
+	}
+}
@@ -0,0 +1,242 @@
+// Command gitup checks and updates Git repositories with their latest tag.
+//
+// Usage:
+//
+//	gitup watch [-config gitup.json] [-http :8080]
+//	gitup log [-config gitup.json] [-file audit.jsonl] [-repo name] [-event update] [-since 24h] [-json]
+//	gitup export [-config gitup.json] -repo name [-basis v1.0.0] file.bundle
+//	gitup import [-config gitup.json] -repo name -sha256 checksum file.bundle
+//
+// The watch command checks the configured repositories on schedule and applies their update strategy.
+// It reloads its configuration on SIGHUP and stops on SIGTERM or SIGINT, once the update in progress is done.
+// With an HTTP address, it serves the control API, authenticated with the token of $GITUP_TOKEN
+// or read-only without it, on /hooks, the push webhooks signed with the secret of $GITUP_WEBHOOK_SECRET if defined,
+// and on /metrics, the metrics in the Prometheus text format.
+//
+// The log command lists the records of the audit log, by default the one of the configuration file.
+//
+// The export and import commands update the air-gapped hosts: export writes a Git bundle of the tags
+// not reachable from the basis, with its manifest file.bundle.json. Once both copied on the host,
+// import verifies the bundle against the checksum printed by export and the signature of its tags
+// against the keys trusted by Git on the host, then applies the update strategy of the repository.
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"net/http"
+	"os"
+	"os/signal"
+	"sort"
+	"strings"
+	"syscall"
+	"text/tabwriter"
+	"time"
+
+	"github.com/rvflash/gitup"
+	"github.com/rvflash/gitup/server"
+	"github.com/rvflash/gitup/webhook"
+)
+
+const (
+	defaultConfig = "gitup.json"
+	envToken      = "GITUP_TOKEN"
+	envSecret     = "GITUP_WEBHOOK_SECRET"
+)
+
+const (
+	errMsgNoAuditLog = "no audit log configured"
+	errMsgBundleArgs = "a repository and a bundle file are required"
+	errMsgImportArgs = "a repository, the checksum of the bundle and its file are required"
+)
+
+// commands lists the available sub-commands.
+var commands = map[string]func(args []string) error{
+	"watch":  watch,
+	"log":    auditLog,
+	"export": exportBundle,
+	"import": importBundle,
+}
+
+func main() {
+	if len(os.Args) < 2 {
+		usage()
+	}
+	cmd, ok := commands[os.Args[1]]
+	if !ok {
+		usage()
+	}
+	if err := cmd(os.Args[2:]); err != nil {
+		fmt.Fprintf(os.Stderr, "gitup: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+// usage prints the list of commands and exits.
+func usage() {
+	fmt.Fprint(os.Stderr, "usage: gitup <command> [arguments]\n\n")
+	fmt.Fprint(os.Stderr, "commands:\n")
+	fmt.Fprint(os.Stderr, "  watch   checks on schedule the configured repositories\n")
+	fmt.Fprint(os.Stderr, "  log     lists the checks and updates of the audit log\n")
+	fmt.Fprint(os.Stderr, "  export  writes a bundle of the latest tags for an air-gapped host\n")
+	fmt.Fprint(os.Stderr, "  import  updates a repository with a bundle\n")
+	os.Exit(2)
+}
+
+// watch runs the watcher until SIGTERM or SIGINT.
+func watch(args []string) error {
+	fs := flag.NewFlagSet("watch", flag.ExitOnError)
+	conf := fs.String("config", defaultConfig, "path of the configuration file")
+	addr := fs.String("http", "", "address of the HTTP control API, disabled if empty")
+	fs.Parse(args)
+
+	w := gitup.NewWatcher(*conf)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	if *addr != "" {
+		api := server.New(w.Manager, os.Getenv(envToken))
+		mux := http.NewServeMux()
+		mux.Handle("/repos", api)
+		mux.Handle("/repos/", api)
+		mux.Handle("/metrics", server.Metrics(w.Manager))
+		if secret := os.Getenv(envSecret); secret != "" {
+			hooks, err := webhook.New(w, secret)
+			if err != nil {
+				return err
+			}
+			mux.Handle("/hooks", hooks)
+		}
+		srv := &http.Server{Addr: *addr, Handler: mux}
+		go func() {
+			if err := srv.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+				fmt.Fprintf(os.Stderr, "gitup: %v\n", err)
+				cancel()
+			}
+		}()
+		defer srv.Shutdown(context.Background())
+	}
+
+	sig := make(chan os.Signal, 1)
+	signal.Notify(sig, syscall.SIGHUP, syscall.SIGTERM, os.Interrupt)
+	defer signal.Stop(sig)
+	go func() {
+		for s := range sig {
+			if s != syscall.SIGHUP {
+				cancel()
+				return
+			}
+			w.Reload()
+		}
+	}()
+	return w.Run(ctx)
+}
+
+// auditLog prints the records of the audit log matching the filters.
+func auditLog(args []string) error {
+	fs := flag.NewFlagSet("log", flag.ExitOnError)
+	conf := fs.String("config", defaultConfig, "path of the configuration file defining the audit log")
+	file := fs.String("file", "", "path of the audit log, instead of the one of the configuration")
+	repo := fs.String("repo", "", "only lists the records of this repository")
+	event := fs.String("event", "", "only lists the records of this event: check, confirm, update, rollback or hook")
+	since := fs.Duration("since", 0, "only lists the records of this last period, like 24h")
+	asJSON := fs.Bool("json", false, "prints the records as JSON Lines")
+	fs.Parse(args)
+
+	path := *file
+	if path == "" {
+		c, err := gitup.LoadConfig(*conf)
+		if err != nil {
+			return err
+		}
+		if path = c.AuditLog; path == "" {
+			return errors.New(errMsgNoAuditLog)
+		}
+	}
+	f := gitup.AuditFilter{Repo: *repo, Event: *event}
+	if *since > 0 {
+		f.Since = time.Now().Add(-*since)
+	}
+	recs, err := gitup.NewAuditLog(path).Records(f)
+	if err != nil {
+		return err
+	}
+	if *asJSON {
+		enc := json.NewEncoder(os.Stdout)
+		for _, rec := range recs {
+			if err = enc.Encode(rec); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	tw := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "TIME\tREPO\tEVENT\tOLD\tNEW\tACTOR\tOUTCOME\tDETAIL")
+	for _, rec := range recs {
+		detail := rec.Err
+		if rec.Hook != "" {
+			detail = strings.TrimSpace(rec.Hook + " " + rec.Command + " " + rec.Err)
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\t%s\t%s\t%s\n",
+			rec.Time.Format(time.RFC3339), rec.Repo, rec.Event, rec.Old, rec.New, rec.Actor, rec.Outcome, detail)
+	}
+	return tw.Flush()
+}
+
+// exportBundle writes the bundle of a repository and its manifest.
+func exportBundle(args []string) error {
+	fs := flag.NewFlagSet("export", flag.ExitOnError)
+	conf := fs.String("config", defaultConfig, "path of the configuration file")
+	repo := fs.String("repo", "", "name of the repository")
+	basis := fs.String("basis", "", "version of the air-gapped host, only the tags not reachable from it are exported")
+	fs.Parse(args)
+
+	if *repo == "" || fs.NArg() != 1 {
+		return errors.New(errMsgBundleArgs)
+	}
+	m, err := manager(*conf)
+	if err != nil {
+		return err
+	}
+	mf, err := m.ExportBundle(*repo, fs.Arg(0), *basis)
+	if err != nil {
+		return err
+	}
+	tags := make([]string, 0, len(mf.Tags))
+	for tag := range mf.Tags {
+		tags = append(tags, tag)
+	}
+	sort.Strings(tags)
+	fmt.Printf("%s: %s (sha256 %s)\n", fs.Arg(0), strings.Join(tags, ", "), mf.SHA256)
+	return nil
+}
+
+// importBundle updates a repository with a bundle, verified with its checksum and its signed tags.
+func importBundle(args []string) error {
+	fs := flag.NewFlagSet("import", flag.ExitOnError)
+	conf := fs.String("config", defaultConfig, "path of the configuration file")
+	repo := fs.String("repo", "", "name of the repository")
+	sum := fs.String("sha256", "", "checksum of the bundle, as printed by export")
+	fs.Parse(args)
+
+	if *repo == "" || *sum == "" || fs.NArg() != 1 {
+		return errors.New(errMsgImportArgs)
+	}
+	m, err := manager(*conf)
+	if err != nil {
+		return err
+	}
+	return m.ImportBundle(*repo, fs.Arg(0), *sum)
+}
+
+// manager returns the manager of the repositories of the configuration file.
+func manager(path string) (*gitup.Manager, error) {
+	c, err := gitup.LoadConfig(path)
+	if err != nil {
+		return nil, err
+	}
+	return c.Manager()
+}
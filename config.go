@@ -0,0 +1,420 @@
+package gitup
+
+import (
+	"encoding/json"
+	"errors"
+	"net"
+	"net/smtp"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/rvflash/gitup/forge"
+	"github.com/rvflash/gitup/goproxy"
+	"github.com/rvflash/gitup/tarball"
+)
+
+// Error messages.
+const (
+	errMsgDuration   = "not a valid duration"
+	errMsgActionName = "unknown action's name"
+	errMsgRepoName   = "duplicated or undefined repository's name"
+	errMsgNotifier   = "undefined notifier"
+	errMsgNoSource   = "tarball requires releases or goproxy as source of the versions"
+)
+
+// Duration is a time.Duration read in JSON from a string like "1h30m".
+type Duration time.Duration
+
+// UnmarshalJSON implements the json.Unmarshaler interface.
+func (d *Duration) UnmarshalJSON(data []byte) error {
+	var str string
+	if err := json.Unmarshal(data, &str); err != nil {
+		return errors.New(errMsgDuration)
+	}
+	v, err := time.ParseDuration(str)
+	if err != nil {
+		return errors.New(errMsgDuration)
+	}
+	*d = Duration(v)
+	return nil
+}
+
+// MarshalJSON implements the json.Marshaler interface.
+func (d Duration) MarshalJSON() ([]byte, error) {
+	return json.Marshal(time.Duration(d).String())
+}
+
+// Config represents the configuration of a set of repositories to watch.
+// @example {"interval": "1h", "jitter": "5m", "repos": [{"path": "/srv/app", "strategy": {"patch": "auto"}}]}
+type Config struct {
+	Interval Duration     `json:"interval"`
+	Cron     string       `json:"cron"`
+	Jitter   Duration     `json:"jitter"`
+	Retry    Duration     `json:"retry"`
+	AuditLog string       `json:"audit_log"`
+	Repos    []RepoConfig `json:"repos"`
+}
+
+// RepoConfig represents the configuration of a repository.
+// The name is by default the base of the path.
+type RepoConfig struct {
+	Name        string              `json:"name"`
+	Path        string              `json:"path"`
+	Strategy    StrategyConfig      `json:"strategy"`
+	Stepwise    bool                `json:"stepwise"`
+	Checkpoints []string            `json:"checkpoints"`
+	Migrations  string              `json:"migrations"`
+	Windows     []WindowConfig      `json:"windows"`
+	Rollout     *RolloutConfig      `json:"rollout"`
+	Hooks       map[string][]string `json:"hooks"`
+	Notify      []NotifyConfig      `json:"notify"`
+	GoProxy     *GoProxyConfig      `json:"goproxy"`
+	Releases    *ReleasesConfig     `json:"releases"`
+	Tarball     *TarballConfig      `json:"tarball"`
+	Clone       *CloneConfig        `json:"clone"`
+	Worktrees   *WorktreesConfig    `json:"worktrees"`
+}
+
+// WorktreesConfig represents the directory where each version is checked out in its own Git worktree,
+// with the symbolic link "current" on the installed one. Keep is the number of worktrees kept on disk.
+// @example {"dir": "/srv/app/versions", "keep": 3}
+type WorktreesConfig struct {
+	Dir  string `json:"dir"`
+	Keep int    `json:"keep"`
+}
+
+// CloneConfig represents the remote cloned in the path of the repository if it is missing,
+// optionally shallow with a depth or partial with a filter. Once cloned, the latest version reached
+// by the strategy from the version is checked out, or without version, the latest stable one.
+// @example {"url": "https://github.com/rvflash/gitup.git", "depth": 1, "version": "v1.4.0"}
+type CloneConfig struct {
+	URL     string `json:"url"`
+	Depth   int    `json:"depth"`
+	Filter  string `json:"filter"`
+	Version string `json:"version"`
+}
+
+// TarballConfig represents the release archives installed in the path of the repository, instead of a Git clone.
+// It requires the releases of a forge or a module proxy as source of the versions.
+// @example {"url": "https://example.com/app/{version}/app.tar.gz", "sums": "https://example.com/app/{version}/SHA256SUMS"}
+type TarballConfig struct {
+	URL             string `json:"url"`
+	Sums            string `json:"sums"`
+	StripComponents int    `json:"strip_components"`
+}
+
+// GoProxyConfig represents the Go module whose versions are read on module proxies instead of the Git remote.
+// The list of proxies and the private patterns are by default the ones of GOPROXY and GOPRIVATE.
+// @example {"module": "example.com/app", "proxy": "https://proxy.example.com,direct", "private": "example.com/internal"}
+type GoProxyConfig struct {
+	Module  string `json:"module"`
+	Proxy   string `json:"proxy"`
+	Private string `json:"private"`
+}
+
+// StrategyConfig represents by type of version the action to perform: noop, manual or auto.
+type StrategyConfig struct {
+	Major      string `json:"major"`
+	Minor      string `json:"minor"`
+	Patch      string `json:"patch"`
+	PreRelease string `json:"pre-release"`
+}
+
+// WindowConfig represents a maintenance window.
+// @example {"days": "mon-fri", "start": "02:00", "end": "04:00", "timezone": "Europe/Paris"}
+type WindowConfig struct {
+	Days     string `json:"days"`
+	Start    string `json:"start"`
+	End      string `json:"end"`
+	TimeZone string `json:"timezone"`
+}
+
+// RolloutConfig represents a staged rollout: by type of version, the steps of the rollout.
+// The host's identifier is by default its hostname.
+// @example {"minor": [{"after": "0s", "percent": 10}, {"after": "48h", "percent": 100}]}
+type RolloutConfig struct {
+	HostID     string              `json:"host_id"`
+	Major      []RolloutStepConfig `json:"major"`
+	Minor      []RolloutStepConfig `json:"minor"`
+	Patch      []RolloutStepConfig `json:"patch"`
+	PreRelease []RolloutStepConfig `json:"pre-release"`
+}
+
+// RolloutStepConfig represents a step of a staged rollout.
+type RolloutStepConfig struct {
+	After   Duration `json:"after"`
+	Percent uint8    `json:"percent"`
+}
+
+// ReleasesConfig represents the releases of a forge used as versions instead of the Git tags.
+// The token is read in the environment variable named by TokenEnv.
+// @example {"forge": "github", "repo": "rvflash/gitup", "token_env": "GITHUB_TOKEN"}
+type ReleasesConfig struct {
+	Forge       string `json:"forge"`
+	URL         string `json:"url"`
+	Repo        string `json:"repo"`
+	TokenEnv    string `json:"token_env"`
+	PreReleases bool   `json:"prereleases"`
+}
+
+// NotifyConfig represents a notifier: a JSON webhook, a Slack incoming webhook, an email or a command.
+// Only one of them is expected by notifier.
+// @example [{"slack": "https://hooks.slack.com/services/T0/B0/XX"}, {"email": {"addr": "localhost:25", "to": ["ops@example.com"]}}]
+type NotifyConfig struct {
+	Webhook string       `json:"webhook"`
+	Slack   string       `json:"slack"`
+	Command string       `json:"command"`
+	Email   *EmailConfig `json:"email"`
+}
+
+// EmailConfig represents the SMTP server and the recipients of the notifications.
+// Without username, the server is used without authentication.
+type EmailConfig struct {
+	Addr     string   `json:"addr"`
+	Username string   `json:"username"`
+	Password string   `json:"password"`
+	From     string   `json:"from"`
+	To       []string `json:"to"`
+}
+
+// Notifier returns the configured notifier.
+func (c NotifyConfig) Notifier() (Notifier, error) {
+	switch {
+	case c.Webhook != "":
+		return Webhook{URL: c.Webhook}, nil
+	case c.Slack != "":
+		return Slack{URL: c.Slack}, nil
+	case c.Command != "":
+		return Command(c.Command), nil
+	case c.Email != nil && c.Email.Addr != "" && len(c.Email.To) > 0:
+		m := Mail{Addr: c.Email.Addr, From: c.Email.From, To: c.Email.To}
+		if c.Email.Username != "" {
+			host, _, _ := net.SplitHostPort(c.Email.Addr)
+			m.Auth = smtp.PlainAuth("", c.Email.Username, c.Email.Password, host)
+		}
+		return m, nil
+	}
+	return nil, errors.New(errMsgNotifier)
+}
+
+// LoadConfig returns the configuration read from the JSON file.
+func LoadConfig(path string) (*Config, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	c := new(Config)
+	if err = json.NewDecoder(f).Decode(c); err != nil {
+		return nil, err
+	}
+	return c, c.validate()
+}
+
+// validate returns an error if a repository has no path or shares its name with another.
+func (c *Config) validate() error {
+	names := make(map[string]bool, len(c.Repos))
+	for i := range c.Repos {
+		if c.Repos[i].Name == "" {
+			c.Repos[i].Name = filepath.Base(strings.TrimSpace(c.Repos[i].Path))
+		}
+		name := c.Repos[i].Name
+		if name == "" || name == "." || names[name] {
+			return errors.New(errMsgRepoName)
+		}
+		names[name] = true
+	}
+	return nil
+}
+
+// UpdateStrategy returns the update strategy of the repository.
+func (c RepoConfig) UpdateStrategy() (s UpdateStrategy, err error) {
+	for version, name := range []string{c.Strategy.Major, c.Strategy.Minor, c.Strategy.Patch, c.Strategy.PreRelease} {
+		if name == "" {
+			continue
+		}
+		var action uint8
+		if action, err = parseAction(name); err != nil {
+			return
+		}
+		if err = s.AddStrategy(uint8(version), action); err != nil {
+			return
+		}
+	}
+	for _, tag := range c.Checkpoints {
+		if err = s.AddCheckpoint(tag); err != nil {
+			return
+		}
+	}
+	for _, wc := range c.Windows {
+		var w Window
+		if w, err = ParseWindow(wc.Days, wc.Start, wc.End, wc.TimeZone); err != nil {
+			return
+		}
+		s.AddWindow(w)
+	}
+	if c.Rollout != nil {
+		var ro *Rollout
+		if ro, err = c.Rollout.NewRollout(); err != nil {
+			return
+		}
+		s.SetRollout(ro)
+	}
+	s.SetStepwise(c.Stepwise)
+	return
+}
+
+// NewRollout returns the staged rollout.
+func (c RolloutConfig) NewRollout() (*Rollout, error) {
+	r, err := NewRollout(c.HostID)
+	if err != nil {
+		return nil, err
+	}
+	for version, steps := range [][]RolloutStepConfig{c.Major, c.Minor, c.Patch, c.PreRelease} {
+		for _, step := range steps {
+			if err = r.AddStep(uint8(version), time.Duration(step.After), step.Percent); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return r, nil
+}
+
+// NewRepo returns the repository with its migrations and hooks.
+func (c RepoConfig) NewRepo() (r *Repo, err error) {
+	var s Source
+	if c.Tarball == nil {
+		if r, err = c.gitRepo(); err != nil {
+			return nil, err
+		}
+		if s, err = c.source(r.git); err != nil {
+			return nil, err
+		}
+		if s != nil {
+			r.SetSource(s)
+		}
+		if c.Worktrees != nil {
+			if err = r.SetWorktrees(c.Worktrees.Dir, c.Worktrees.Keep); err != nil {
+				return nil, err
+			}
+		}
+	} else {
+		// Without Git clone, the versions come from the module proxy or the forge.
+		if s, err = c.source(nil); err != nil {
+			return nil, err
+		}
+		if s == nil {
+			return nil, errors.New(errMsgNoSource)
+		}
+		var i *tarball.Installer
+		if i, err = tarball.New(c.Path, c.Tarball.URL, c.Tarball.Sums); err != nil {
+			return nil, err
+		}
+		i.StripComponents = c.Tarball.StripComponents
+		if r, err = NewRepoFrom(c.Path, s, i); err != nil {
+			return nil, err
+		}
+	}
+	r.SetMigrationDir(c.Migrations)
+	for name, commands := range c.Hooks {
+		point, err := parseHookPoint(name)
+		if err != nil {
+			return nil, err
+		}
+		for _, cmd := range commands {
+			r.AddHook(point, Command(cmd))
+		}
+	}
+	for _, nc := range c.Notify {
+		n, err := nc.Notifier()
+		if err != nil {
+			return nil, err
+		}
+		r.AddNotifier(n)
+	}
+	return r, nil
+}
+
+// gitRepo returns the Git repository of the path, cloned first if it is missing and configured so.
+func (c RepoConfig) gitRepo() (*Repo, error) {
+	if c.Clone == nil {
+		return NewRepo(c.Path)
+	}
+	s, err := c.UpdateStrategy()
+	if err != nil {
+		return nil, err
+	}
+	return Clone(c.Clone.URL, c.Path, s, CloneOptions{Depth: c.Clone.Depth, Filter: c.Clone.Filter, Version: c.Clone.Version})
+}
+
+// source returns the configured source of the versions, nil to use the tags of the Git repository.
+// With "direct", the module proxy uses the Git repository, if any.
+func (c RepoConfig) source(git GitFlow) (Source, error) {
+	switch {
+	case c.Releases != nil:
+		s, err := forge.New(c.Releases.Forge, c.Releases.URL, c.Releases.Repo, os.Getenv(c.Releases.TokenEnv))
+		if err != nil {
+			return nil, err
+		}
+		s.SetPreReleases(c.Releases.PreReleases)
+		return s, nil
+	case c.GoProxy != nil:
+		var direct goproxy.Source
+		if git != nil {
+			direct = git
+		}
+		s, err := goproxy.New(c.GoProxy.Module, c.GoProxy.Proxy, c.GoProxy.Private, direct)
+		if err != nil {
+			return nil, err
+		}
+		return s, nil
+	}
+	return nil, nil
+}
+
+// Manager returns a manager of the configured repositories.
+func (c *Config) Manager() (*Manager, error) {
+	m := NewManager()
+	var audit *AuditLog
+	if c.AuditLog != "" {
+		audit = NewAuditLog(c.AuditLog)
+	}
+	for _, rc := range c.Repos {
+		s, err := rc.UpdateStrategy()
+		if err != nil {
+			return nil, err
+		}
+		r, err := rc.NewRepo()
+		if err != nil {
+			return nil, err
+		}
+		r.SetAuditLog(audit)
+		if err = m.Add(rc.Name, r, s); err != nil {
+			return nil, err
+		}
+	}
+	return m, nil
+}
+
+// parseAction returns the action named noop, manual or auto.
+func parseAction(name string) (uint8, error) {
+	for action, n := range actionNames {
+		if strings.EqualFold(n, name) {
+			return uint8(action), nil
+		}
+	}
+	return Noop, errors.New(errMsgActionName)
+}
+
+// parseHookPoint returns the hook point named before_check, before_update, after_update or on_error.
+func parseHookPoint(name string) (uint8, error) {
+	for point, n := range hookNames {
+		if n == name {
+			return uint8(point), nil
+		}
+	}
+	return 0, errors.New(errMsgHookPoint)
+}
@@ -0,0 +1,175 @@
+package gitup
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+var configTests = []struct {
+	data  string // input
+	onErr bool   // expected result
+}{
+	{`{"interval": "1h", "repos": [{"path": "/srv/app"}, {"name": "api", "path": "/srv/app"}]}`, false},
+	{`{"interval": 3600}`, true},
+	{`{"interval": "1 hour"}`, true},
+	{`{"repos": [{"path": "/srv/app"}, {"path": "/home/app"}]}`, true},
+	{`{"repos": [{"path": ""}]}`, true},
+	{`{"repos": [`, true},
+}
+
+var strategyConfigTests = []struct {
+	conf     RepoConfig // input
+	strategy [4]uint8   // expected result
+	onErr    bool
+}{
+	{RepoConfig{}, [4]uint8{}, false},
+	{RepoConfig{Strategy: StrategyConfig{Minor: "manual", Patch: "Auto"}}, [4]uint8{Noop, Manual, Auto}, false},
+	{RepoConfig{Strategy: StrategyConfig{Major: "auto", Patch: "manual"}}, [4]uint8{}, true},
+	{RepoConfig{Strategy: StrategyConfig{Major: "always"}}, [4]uint8{}, true},
+	{RepoConfig{Checkpoints: []string{"1.0"}}, [4]uint8{}, true},
+}
+
+// TestLoadConfig tests LoadConfig method with valid or invalid JSON files.
+func TestLoadConfig(t *testing.T) {
+	if _, err := LoadConfig("/unknown/gitup.json"); err == nil {
+		t.Error("Expected error with unknown file")
+	}
+	for _, ct := range configTests {
+		f, err := ioutil.TempFile(os.TempDir(), "gitup")
+		if err != nil {
+			t.Fatalf("Unable to create config file, received error: %v", err)
+		}
+		f.WriteString(ct.data)
+		f.Close()
+		c, err := LoadConfig(f.Name())
+		if err != nil {
+			if !ct.onErr {
+				t.Errorf("Expected no error with %v, received: %v", ct.data, err)
+			}
+		} else if ct.onErr {
+			t.Errorf("Expected error with %v", ct.data)
+		} else if c.Repos[0].Name != "app" || c.Repos[1].Name != "api" || time.Duration(c.Interval) != time.Hour {
+			t.Errorf("Expected named repositories with interval, received: %#v", c)
+		}
+		os.Remove(f.Name())
+	}
+}
+
+// TestRepoConfig_UpdateStrategy tests the strategy built from the configuration.
+func TestRepoConfig_UpdateStrategy(t *testing.T) {
+	for _, st := range strategyConfigTests {
+		if s, err := st.conf.UpdateStrategy(); err != nil {
+			if !st.onErr {
+				t.Errorf("Expected no error with %#v, received: %v", st.conf, err)
+			}
+		} else if st.onErr {
+			t.Errorf("Expected error with %#v", st.conf)
+		} else if s.until != st.strategy {
+			t.Errorf("Expected strategy %v with %#v, received: %v", st.strategy, st.conf, s.until)
+		}
+	}
+	s, _ := RepoConfig{Stepwise: true, Checkpoints: []string{"v1.2.0"}}.UpdateStrategy()
+	if !s.stepwise || !s.isCheckpoint("v1.2.0") {
+		t.Errorf("Expected stepwise strategy with checkpoint, received: %#v", s)
+	}
+}
+
+// TestDuration_JSON tests the JSON encoding of a duration.
+func TestDuration_JSON(t *testing.T) {
+	d := Duration(90 * time.Minute)
+	b, err := json.Marshal(d)
+	if err != nil || string(b) != `"1h30m0s"` {
+		t.Errorf("Expected JSON duration, received: %s (%v)", b, err)
+	}
+	var res Duration
+	if err := json.Unmarshal(b, &res); err != nil || res != d {
+		t.Errorf("Expected duration %v, received: %v (%v)", d, res, err)
+	}
+}
+
+// TestParseHookPoint tests the hook points by name.
+func TestParseHookPoint(t *testing.T) {
+	for point, name := range hookNames {
+		if p, err := parseHookPoint(name); err != nil || p != uint8(point) {
+			t.Errorf("Expected hook point %v for %v, received: %v (%v)", point, name, p, err)
+		}
+	}
+	if _, err := parseHookPoint("after_check"); err == nil {
+		t.Error("Expected error with unknown hook point")
+	}
+}
+
+// TestRepoConfig_NewRepo_Tarball tests the repository installed from release archives, without Git clone.
+func TestRepoConfig_NewRepo_Tarball(t *testing.T) {
+	tb := &TarballConfig{URL: "https://example.com/{version}/app.tar.gz", Sums: "https://example.com/{version}/SHA256SUMS"}
+	if _, err := (RepoConfig{Path: os.TempDir(), Tarball: tb}).NewRepo(); err == nil {
+		t.Error("Expected error without source of the versions")
+	}
+	c := RepoConfig{Path: os.TempDir(), Tarball: tb, GoProxy: &GoProxyConfig{Module: "example.com/app", Proxy: "off"}}
+	if r, err := c.NewRepo(); err != nil || r.git != nil || r.source == nil || r.installer == nil {
+		t.Errorf("Expected repository without Git, received: %#v, %v", r, err)
+	}
+}
+
+// TestRepoConfig_NewRepo_GoProxy tests the update of a Git repository on a version listed by a module proxy.
+func TestRepoConfig_NewRepo_GoProxy(t *testing.T) {
+	remote := newRemote(t, "v1.0.0")
+	defer os.RemoveAll(remote)
+
+	path := filepath.Join(remote, ".clones", "app")
+	if _, err := Clone("file://"+remote, path, UpdateStrategy{}, CloneOptions{}); err != nil {
+		t.Fatalf("Expected no error, received: %v", err)
+	}
+	// The new version is only known by the remote and the proxy.
+	runGit(t, remote, nil, "commit", "-q", "--allow-empty", "-m", "v1.1.0")
+	runGit(t, remote, nil, "tag", "v1.1.0")
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/example.com/app/@v/list":
+			fmt.Fprint(w, "v1.0.0\nv1.1.0\n")
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer srv.Close()
+
+	c := RepoConfig{Path: path, GoProxy: &GoProxyConfig{Module: "example.com/app", Proxy: srv.URL, Private: "none"}}
+	r, err := c.NewRepo()
+	if err != nil {
+		t.Fatalf("Expected no error, received: %v", err)
+	}
+	m := NewManager()
+	m.Add("app", r, UpdateStrategy{until: [4]uint8{Noop, Auto}})
+	if err = m.Apply("app"); err != nil {
+		t.Fatalf("Expected no error, received: %v", err)
+	}
+	if tag, err := r.git.LocalTag(); err != nil || tag != "v1.1.0" {
+		t.Errorf("Expected v1.1.0 fetched then checked out, received: %q, %v", tag, err)
+	}
+}
+
+// TestRepoConfig_NewRepo_Clone tests the bootstrap of a missing repository by configuration.
+func TestRepoConfig_NewRepo_Clone(t *testing.T) {
+	remote := newRemote(t, "v1.0.0", "v1.0.1", "v1.1.0")
+	defer os.RemoveAll(remote)
+
+	c := RepoConfig{
+		Path:     filepath.Join(remote, ".clones", "app"),
+		Strategy: StrategyConfig{Patch: "auto"},
+		Clone:    &CloneConfig{URL: "file://" + remote, Depth: 1, Version: "v1.0.0"},
+	}
+	r, err := c.NewRepo()
+	if err != nil {
+		t.Fatalf("Expected no error, received: %v", err)
+	}
+	if tag, _ := r.git.LocalTag(); tag != "v1.0.1" {
+		t.Errorf("Expected v1.0.1, received: %q", tag)
+	}
+}
@@ -0,0 +1,170 @@
+package gitup
+
+import (
+	"context"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/rvflash/gitup/internal/semver"
+)
+
+// EventBuffer is the number of events kept for a slow consumer, the next ones are dropped.
+const EventBuffer = 64
+
+// Event is emitted along the lifecycle of a repository.
+type Event interface {
+	Info() EventInfo
+}
+
+// EventInfo describes the repository and the time of an event.
+type EventInfo struct {
+	Name string
+	Path string
+	Time time.Time
+}
+
+// Info implements the Event interface.
+func (e EventInfo) Info() EventInfo {
+	return e
+}
+
+// CheckStarted is emitted when a check starts.
+type CheckStarted struct {
+	EventInfo
+}
+
+// CheckFailed is emitted when a check fails.
+type CheckFailed struct {
+	EventInfo
+	Err error
+}
+
+// UpdateAvailable is emitted when a check finds a version to update to, according to the strategy.
+type UpdateAvailable struct {
+	EventInfo
+	Local, Remote string
+	Relationship  semver.Relationship
+}
+
+// UpdateDeferred is emitted when an automatic update is queued until the next maintenance window.
+type UpdateDeferred struct {
+	EventInfo
+	From, To string
+}
+
+// UpdateApplied is emitted when the repository has been updated.
+type UpdateApplied struct {
+	EventInfo
+	From, To string
+}
+
+// UpdateFailed is emitted when an update fails.
+type UpdateFailed struct {
+	EventInfo
+	From, To string
+	Err      error
+}
+
+// RolledBack is emitted when the repository has been moved back on its previous version.
+type RolledBack struct {
+	EventInfo
+	From, To string
+}
+
+// broker dispatches the events to the subscribers without blocking.
+type broker struct {
+	mu     sync.Mutex
+	subs   map[chan Event]struct{}
+	parent *broker
+}
+
+// newBroker returns a broker without subscriber.
+func newBroker() *broker {
+	return &broker{subs: make(map[chan Event]struct{})}
+}
+
+// subscribe returns a channel receiving the events until the context is done.
+func (b *broker) subscribe(ctx context.Context) <-chan Event {
+	ch := make(chan Event, EventBuffer)
+	b.mu.Lock()
+	b.subs[ch] = struct{}{}
+	b.mu.Unlock()
+	go func() {
+		<-ctx.Done()
+		b.mu.Lock()
+		delete(b.subs, ch)
+		close(ch)
+		b.mu.Unlock()
+	}()
+	return ch
+}
+
+// publish sends the event to each subscriber able to receive it, then to the parent broker.
+func (b *broker) publish(e Event) {
+	if b == nil {
+		return
+	}
+	b.mu.Lock()
+	for ch := range b.subs {
+		select {
+		case ch <- e:
+		default:
+			// Slow consumer, drops the event.
+		}
+	}
+	parent := b.parent
+	b.mu.Unlock()
+	parent.publish(e)
+}
+
+// setParent defines the broker receiving all the events of this one.
+func (b *broker) setParent(parent *broker) {
+	b.mu.Lock()
+	b.parent = parent
+	b.mu.Unlock()
+}
+
+// Watch returns a channel receiving the events of the repository until the context is done.
+// A slow consumer does not block the repository: once its buffer is full, the new events are dropped.
+func (r *Repo) Watch(ctx context.Context) <-chan Event {
+	return r.broker().subscribe(ctx)
+}
+
+// Events returns a channel receiving the events of all the repositories until the context is done.
+// A slow consumer does not block the repositories: once its buffer is full, the new events are dropped.
+func (m *Manager) Events(ctx context.Context) <-chan Event {
+	return m.events.subscribe(ctx)
+}
+
+// brokerMu protects the creation on demand of the brokers.
+var brokerMu sync.Mutex
+
+// broker returns the broker of the repository, created on demand.
+func (r *Repo) broker() *broker {
+	brokerMu.Lock()
+	defer brokerMu.Unlock()
+
+	if r.events == nil {
+		r.events = newBroker()
+	}
+	return r.events
+}
+
+// publish emits an event of the repository.
+func (r *Repo) publish(e Event) {
+	r.broker().publish(e)
+}
+
+// eventInfo returns the description of the repository for an event emitted now.
+func (r *Repo) eventInfo() EventInfo {
+	return EventInfo{Name: r.displayName(), Path: r.path, Time: time.Now()}
+}
+
+// displayName returns the name of the repository, by default the base of its path.
+func (r *Repo) displayName() string {
+	if r.name == "" && r.path != "" {
+		return filepath.Base(r.path)
+	}
+	return r.name
+}
@@ -0,0 +1,119 @@
+package gitup
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+)
+
+// eventNames returns the type name of each event received until the channel is empty.
+func eventNames(ch <-chan Event) string {
+	var names []string
+	for {
+		select {
+		case e := <-ch:
+			names = append(names, strings.TrimPrefix(fmt.Sprintf("%T", e), "gitup."))
+		default:
+			return strings.Join(names, ",")
+		}
+	}
+}
+
+// TestRepo_Watch tests the events emitted by a repository.
+func TestRepo_Watch(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	git := &FakeStepFlow{FakeGitFlow: FakeGitFlow{localTag: "v1.0.0", remoteTag: "v1.1.0"}}
+	r := &Repo{git: git, path: "/srv/app"}
+	ch := r.Watch(ctx)
+	if err := r.Update(UpdateStrategy{until: [4]uint8{Auto}}); err != nil {
+		t.Fatalf("Expected no error, received: %v", err)
+	}
+	if err := r.Rollback(); err != nil {
+		t.Fatalf("Expected no error on rollback, received: %v", err)
+	}
+	if err := r.Rollback(); err == nil {
+		t.Error("Expected error without update to roll back")
+	}
+	if names := eventNames(ch); names != "CheckStarted,UpdateAvailable,UpdateApplied,RolledBack" {
+		t.Errorf("Expected lifecycle events, received: %v", names)
+	}
+	if checkouts := strings.Join(git.checkouts, ","); checkouts != "v1.1.0,v1.0.0" || r.local != "v1.0.0" {
+		t.Errorf("Expected rollback on v1.0.0, received: %v", checkouts)
+	}
+	// With failures.
+	r = &Repo{git: &FakeGitFlow{localTag: "v1.0.0", remoteTag: "v1.1.0", checkoutError: true}}
+	ch = r.Watch(ctx)
+	r.Update(UpdateStrategy{until: [4]uint8{Auto}})
+	if names := eventNames(ch); names != "CheckStarted,UpdateAvailable,UpdateFailed" {
+		t.Errorf("Expected failure events, received: %v", names)
+	}
+	r = &Repo{git: &FakeGitFlow{localTag: "v1.0.0", remoteError: true}}
+	ch = r.Watch(ctx)
+	r.InDemand(UpdateStrategy{until: [4]uint8{Auto}})
+	if names := eventNames(ch); names != "CheckStarted,CheckFailed" {
+		t.Errorf("Expected check failure events, received: %v", names)
+	}
+}
+
+// TestManager_Events tests the events of all the repositories.
+func TestManager_Events(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+
+	m := NewManager()
+	m.Add("app", &Repo{git: &FakeStepFlow{FakeGitFlow: FakeGitFlow{localTag: "v1.0.0", remoteTag: "v1.1.0"}}}, UpdateStrategy{})
+	m.Add("api", &Repo{git: &FakeStepFlow{FakeGitFlow: FakeGitFlow{localTag: "v1.0.0", remoteTag: "v1.1.0"}}}, UpdateStrategy{})
+	ch := m.Events(ctx)
+	m.Check("app")
+	m.Check("api")
+	var names []string
+	for i := 0; i < 2; i++ {
+		names = append(names, (<-ch).Info().Name)
+	}
+	if strings.Join(names, ",") != "app,api" {
+		t.Errorf("Expected events named by repository, received: %v", names)
+	}
+	// The channel is closed once the context is done.
+	cancel()
+	timeout := time.After(time.Second)
+	for {
+		select {
+		case _, ok := <-ch:
+			if !ok {
+				return
+			}
+		case <-timeout:
+			t.Fatal("Expected closed channel")
+		}
+	}
+}
+
+// TestBroker_Publish tests that a slow consumer does not block the publisher.
+func TestBroker_Publish(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	b := newBroker()
+	ch := b.subscribe(ctx)
+	done := make(chan struct{})
+	go func() {
+		for i := 0; i < EventBuffer*2; i++ {
+			b.publish(CheckStarted{})
+		}
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("Expected publisher not blocked by a slow consumer")
+	}
+	if n := len(ch); n != EventBuffer {
+		t.Errorf("Expected %d buffered events, received: %d", EventBuffer, n)
+	}
+	// Without broker.
+	var nb *broker
+	nb.publish(CheckStarted{})
+}
@@ -0,0 +1,300 @@
+// Package forge provides the versions of a repository from the releases published on its forge:
+// GitHub, GitLab or Gitea, with their REST API.
+//
+// Only the published releases are versions: the drafts, the upcoming releases and by default,
+// the pre-releases are ignored, like the tags without release. The releases are listed page by page,
+// following the header Link or X-Next-Page, up to 50 pages. The responses are cached
+// with their ETag, a request whose content has not changed costs neither bandwidth nor rate limit.
+package forge
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/rvflash/gitup/internal/semver"
+)
+
+// Supported forges.
+const (
+	GitHub = "github"
+	GitLab = "gitlab"
+	Gitea  = "gitea"
+)
+
+// Default API's URLs.
+const (
+	GitHubURL = "https://api.github.com"
+	GitLabURL = "https://gitlab.com"
+)
+
+// Error messages.
+const (
+	errMsgForge   = "unknown forge"
+	errMsgURL     = "forge URL is undefined"
+	errMsgRepo    = "repository is undefined"
+	errMsgRelease = "no published release"
+	errMsgTag     = "unknown release"
+	errMsgPages   = "too many pages of releases"
+)
+
+// Pagination: the number of releases requested by page, then the maximum number of pages.
+const (
+	perPage  = 100
+	maxPages = 50
+)
+
+// Release is a published release.
+type Release struct {
+	Tag        string
+	Name       string
+	Notes      string
+	Date       time.Time
+	PreRelease bool
+}
+
+// release is a release as described by GitHub, Gitea or GitLab.
+type release struct {
+	TagName     string    `json:"tag_name"`
+	Name        string    `json:"name"`
+	Body        string    `json:"body"`
+	Description string    `json:"description"`
+	Draft       bool      `json:"draft"`
+	PreRelease  bool      `json:"prerelease"`
+	Upcoming    bool      `json:"upcoming_release"`
+	PublishedAt time.Time `json:"published_at"`
+	ReleasedAt  time.Time `json:"released_at"`
+}
+
+// cached is a response with its ETag and the URL of the next page.
+type cached struct {
+	etag string
+	body []byte
+	next string
+}
+
+// Releases is a source of the versions published as releases on a forge.
+type Releases struct {
+	// Client is the HTTP client used to query the forge, http.DefaultClient if nil.
+	Client      *http.Client
+	forge       string
+	url         string
+	repo        string
+	token       string
+	preReleases bool
+	mu          sync.Mutex
+	cache       map[string]cached
+}
+
+// New returns the source of the releases of the repository, like "rvflash/gitup", on the forge.
+// The URL is the one of the API for GitHub, like "https://github.example.com/api/v3", the one of the instance
+// for GitLab and Gitea. Without URL, the public instance of GitHub or GitLab is used. The token is optional.
+func New(forge, baseURL, repo, token string) (*Releases, error) {
+	if baseURL = strings.TrimSuffix(strings.TrimSpace(baseURL), "/"); baseURL == "" {
+		switch forge {
+		case GitHub:
+			baseURL = GitHubURL
+		case GitLab:
+			baseURL = GitLabURL
+		}
+	}
+	switch forge {
+	case GitHub, GitLab, Gitea:
+	default:
+		return nil, errors.New(errMsgForge)
+	}
+	if baseURL == "" {
+		return nil, errors.New(errMsgURL)
+	}
+	if repo = strings.Trim(strings.TrimSpace(repo), "/"); repo == "" {
+		return nil, errors.New(errMsgRepo)
+	}
+	return &Releases{forge: forge, url: baseURL, repo: repo, token: token, cache: make(map[string]cached)}, nil
+}
+
+// SetPreReleases defines whether the pre-releases are versions.
+func (r *Releases) SetPreReleases(ok bool) {
+	r.preReleases = ok
+}
+
+// Releases returns the published releases, from the most recent.
+func (r *Releases) Releases() ([]Release, error) {
+	var list []release
+	next := r.endpoint()
+	for page := 0; next != ""; page++ {
+		if page == maxPages {
+			return nil, errors.New(errMsgPages)
+		}
+		buf, nextURL, err := r.get(next)
+		if err != nil {
+			return nil, err
+		}
+		var releases []release
+		if err = json.Unmarshal(buf, &releases); err != nil {
+			return nil, err
+		}
+		list, next = append(list, releases...), nextURL
+	}
+	var res []Release
+	for _, rel := range list {
+		if rel.Draft || rel.Upcoming || rel.TagName == "" {
+			continue
+		}
+		v := Release{Tag: rel.TagName, Name: rel.Name, Notes: rel.Body, Date: rel.PublishedAt, PreRelease: rel.PreRelease}
+		if r.forge == GitLab {
+			// GitLab has no flag of pre-release.
+			sv, err := semver.Parse(rel.TagName)
+			v.Notes, v.Date, v.PreRelease = rel.Description, rel.ReleasedAt, err == nil && sv.PreRelease != ""
+		}
+		if v.PreRelease && !r.preReleases {
+			continue
+		}
+		res = append(res, v)
+	}
+	return res, nil
+}
+
+// LastTag returns the tag of the latest published release.
+func (r *Releases) LastTag() (string, error) {
+	tags, err := r.Tags()
+	if err != nil {
+		return "", err
+	}
+	if tags = semver.Sort(tags); len(tags) == 0 {
+		return "", errors.New(errMsgRelease)
+	}
+	return tags[len(tags)-1], nil
+}
+
+// Tags returns the tags of the published releases.
+func (r *Releases) Tags() ([]string, error) {
+	list, err := r.Releases()
+	if err != nil {
+		return nil, err
+	}
+	tags := make([]string, len(list))
+	for i, rel := range list {
+		tags[i] = rel.Tag
+	}
+	return tags, nil
+}
+
+// TagDate returns the publication date of the release.
+func (r *Releases) TagDate(tag string) (time.Time, error) {
+	rel, err := r.release(tag)
+	return rel.Date, err
+}
+
+// Notes returns the release notes of the tag.
+func (r *Releases) Notes(tag string) (string, error) {
+	rel, err := r.release(tag)
+	return rel.Notes, err
+}
+
+// release returns the published release of the tag.
+func (r *Releases) release(tag string) (Release, error) {
+	list, err := r.Releases()
+	if err != nil {
+		return Release{}, err
+	}
+	for _, rel := range list {
+		if rel.Tag == tag {
+			return rel, nil
+		}
+	}
+	return Release{}, errors.New(errMsgTag)
+}
+
+// endpoint returns the URL listing the releases.
+func (r *Releases) endpoint() string {
+	switch r.forge {
+	case GitLab:
+		return fmt.Sprintf("%s/api/v4/projects/%s/releases?per_page=%d", r.url, url.PathEscape(r.repo), perPage)
+	case Gitea:
+		return fmt.Sprintf("%s/api/v1/repos/%s/releases?limit=%d", r.url, r.repo, perPage)
+	}
+	return fmt.Sprintf("%s/repos/%s/releases?per_page=%d", r.url, r.repo, perPage)
+}
+
+// get returns the content at the URL and the URL of its next page, if any,
+// from the cache if it has not changed since.
+func (r *Releases) get(rawURL string) ([]byte, string, error) {
+	req, err := http.NewRequest(http.MethodGet, rawURL, nil)
+	if err != nil {
+		return nil, "", err
+	}
+	req.Header.Set("Accept", "application/json")
+	if r.token != "" {
+		switch r.forge {
+		case GitLab:
+			req.Header.Set("PRIVATE-TOKEN", r.token)
+		case Gitea:
+			req.Header.Set("Authorization", "token "+r.token)
+		default:
+			req.Header.Set("Authorization", "Bearer "+r.token)
+		}
+	}
+	r.mu.Lock()
+	c, ok := r.cache[rawURL]
+	r.mu.Unlock()
+	if ok {
+		req.Header.Set("If-None-Match", c.etag)
+	}
+	client := r.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, "", err
+	}
+	defer resp.Body.Close()
+	switch {
+	case resp.StatusCode == http.StatusNotModified && ok:
+		return c.body, c.next, nil
+	case resp.StatusCode != http.StatusOK:
+		return nil, "", fmt.Errorf("%s: %s", rawURL, resp.Status)
+	}
+	buf, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, "", err
+	}
+	next := nextPage(rawURL, resp.Header)
+	if etag := resp.Header.Get("ETag"); etag != "" {
+		r.mu.Lock()
+		r.cache[rawURL] = cached{etag: etag, body: buf, next: next}
+		r.mu.Unlock()
+	}
+	return buf, next, nil
+}
+
+// nextPage returns the URL of the page following the one at the URL, empty on the last one.
+// GitHub and Gitea name it in the header Link, GitLab gives its number in the header X-Next-Page.
+func nextPage(rawURL string, h http.Header) string {
+	for _, link := range strings.Split(h.Get("Link"), ",") {
+		parts := strings.Split(link, ";")
+		for _, param := range parts[1:] {
+			if strings.TrimSpace(param) == `rel="next"` {
+				return strings.Trim(strings.TrimSpace(parts[0]), "<>")
+			}
+		}
+	}
+	page := h.Get("X-Next-Page")
+	if page == "" {
+		return ""
+	}
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return ""
+	}
+	q := u.Query()
+	q.Set("page", page)
+	u.RawQuery = q.Encode()
+	return u.String()
+}
@@ -0,0 +1,158 @@
+package forge_test
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/rvflash/gitup/forge"
+)
+
+const tokenTest = "s3cr3t"
+
+// recorded serves the recorded responses of each forge with an ETag, and counts the requests.
+type recorded struct {
+	requests, notModified int
+}
+
+// ServeHTTP implements the http.Handler interface.
+func (rec *recorded) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	rec.requests++
+	var file string
+	var auth bool
+	switch r.URL.Path {
+	case "/repos/rvflash/gitup/releases":
+		file, auth = "github.json", r.Header.Get("Authorization") == "Bearer "+tokenTest
+	case "/api/v1/repos/rvflash/gitup/releases":
+		file, auth = "gitea.json", r.Header.Get("Authorization") == "token "+tokenTest
+	case "/api/v4/projects/rvflash/gitup/releases":
+		file, auth = "gitlab.json", r.Header.Get("PRIVATE-TOKEN") == tokenTest
+	default:
+		http.NotFound(w, r)
+		return
+	}
+	if !auth {
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+	etag := `"` + file + `"`
+	if r.Header.Get("If-None-Match") == etag {
+		rec.notModified++
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+	buf, _ := ioutil.ReadFile(filepath.Join("testdata", file))
+	w.Header().Set("ETag", etag)
+	w.Write(buf)
+}
+
+var releasesTests = []struct {
+	forge       string // input
+	preReleases bool
+	tags        string // expected result
+	last        string
+}{
+	{forge.GitHub, false, "v1.1.0,v1.0.0", "v1.1.0"},
+	{forge.GitHub, true, "v1.2.0-rc.1,v1.1.0,v1.0.0", "v1.2.0-rc.1"},
+	{forge.Gitea, false, "v1.1.0", "v1.1.0"},
+	{forge.GitLab, false, "v1.1.0", "v1.1.0"},
+	{forge.GitLab, true, "v1.2.0-beta,v1.1.0", "v1.2.0-beta"},
+}
+
+// TestReleases tests the published releases of each forge, with their notes and dates.
+func TestReleases(t *testing.T) {
+	rec := new(recorded)
+	srv := httptest.NewServer(rec)
+	defer srv.Close()
+
+	for _, rt := range releasesTests {
+		r, err := forge.New(rt.forge, srv.URL, "rvflash/gitup", tokenTest)
+		if err != nil {
+			t.Fatalf("Expected no error, received: %v", err)
+		}
+		r.SetPreReleases(rt.preReleases)
+		if tags, err := r.Tags(); err != nil || strings.Join(tags, ",") != rt.tags {
+			t.Errorf("Expected tags %v on %v, received: %v, %v", rt.tags, rt.forge, tags, err)
+		}
+		if tag, err := r.LastTag(); err != nil || tag != rt.last {
+			t.Errorf("Expected latest %v on %v, received: %v, %v", rt.last, rt.forge, tag, err)
+		}
+		if notes, err := r.Notes("v1.1.0"); err != nil || notes != "* Adds the stepwise mode." {
+			t.Errorf("Expected release notes on %v, received: %q, %v", rt.forge, notes, err)
+		}
+		if date, err := r.TagDate("v1.1.0"); err != nil || !date.Equal(time.Date(2017, 6, 2, 10, 0, 0, 0, time.UTC)) {
+			t.Errorf("Expected release date on %v, received: %v, %v", rt.forge, date, err)
+		}
+		if _, err := r.Notes("v1.3.0"); err == nil {
+			t.Errorf("Expected error with unpublished release on %v", rt.forge)
+		}
+	}
+	// Only the first request of each source is not cached.
+	if rec.notModified != rec.requests-len(releasesTests) {
+		t.Errorf("Expected cached responses, received: %d on %d requests", rec.notModified, rec.requests)
+	}
+}
+
+// TestNew tests the creation of a source with invalid settings or token.
+func TestNew(t *testing.T) {
+	for _, args := range [][3]string{{"bitbucket", "", "rvflash/gitup"}, {forge.Gitea, "", "rvflash/gitup"}, {forge.GitHub, "", " "}} {
+		if _, err := forge.New(args[0], args[1], args[2], ""); err == nil {
+			t.Errorf("Expected error with %v", args)
+		}
+	}
+	srv := httptest.NewServer(new(recorded))
+	defer srv.Close()
+
+	r, _ := forge.New(forge.GitHub, srv.URL, "rvflash/gitup", "wrong")
+	if _, err := r.LastTag(); err == nil {
+		t.Error("Expected error with invalid token")
+	}
+}
+
+// paged serves one release by page, up to the last one, linked like GitHub or numbered like GitLab.
+type paged struct {
+	last int
+}
+
+// ServeHTTP implements the http.Handler interface.
+func (p paged) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	page, _ := strconv.Atoi(r.URL.Query().Get("page"))
+	if page == 0 {
+		page = 1
+	}
+	if page < p.last {
+		if strings.HasPrefix(r.URL.Path, "/api/v4/") {
+			w.Header().Set("X-Next-Page", strconv.Itoa(page+1))
+		} else {
+			next := fmt.Sprintf("http://%s%s?per_page=100&page=%d", r.Host, r.URL.Path, page+1)
+			w.Header().Set("Link", `<`+next+`>; rel="next", <http://`+r.Host+r.URL.Path+`?page=99>; rel="last"`)
+		}
+	}
+	fmt.Fprintf(w, `[{"tag_name": "v1.%d.0", "published_at": "2017-06-02T10:00:00Z", "released_at": "2017-06-02T10:00:00Z"}]`, page)
+}
+
+// TestReleases_Pages tests that the releases are listed on every page.
+func TestReleases_Pages(t *testing.T) {
+	for _, name := range []string{forge.GitHub, forge.GitLab, forge.Gitea} {
+		srv := httptest.NewServer(paged{last: 3})
+		r, _ := forge.New(name, srv.URL, "rvflash/gitup", "")
+		if tags, err := r.Tags(); err != nil || strings.Join(tags, ",") != "v1.1.0,v1.2.0,v1.3.0" {
+			t.Errorf("Expected the releases of the 3 pages on %v, received: %v, %v", name, tags, err)
+		}
+		srv.Close()
+	}
+	// Beyond the limit of pages, the releases are not truncated silently.
+	srv := httptest.NewServer(paged{last: 1000})
+	defer srv.Close()
+
+	r, _ := forge.New(forge.GitHub, srv.URL, "rvflash/gitup", "")
+	if _, err := r.Tags(); err == nil {
+		t.Error("Expected error with too many pages")
+	}
+}
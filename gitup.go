@@ -5,6 +5,8 @@ import (
 	"errors"
 	"fmt"
 	"os"
+	"strings"
+	"time"
 
 	"github.com/rvflash/gitup/internal/gitflow"
 	"github.com/rvflash/gitup/internal/semver"
@@ -33,26 +35,66 @@ const (
 	errMsgInDemand        = "no available update"
 	errMsgConfirm         = "only accepts yes or no as valid response"
 	errMsgDowngradeAction = "unable to downgrade behavior on minor versions"
+	errMsgRollback        = "no update to roll back"
 )
 
+// ErrNoUpdate is returned when the repository is already on the latest version allowed by the strategy.
+var ErrNoUpdate = errors.New(errMsgInDemand)
+
+// ErrNoRollback is returned when the repository has no previous version to move back on.
+var ErrNoRollback = errors.New(errMsgRollback)
+
+// checkpointMetadata is the build metadata's identifier used to mark a tag as checkpoint.
+// @example v1.4.0+checkpoint
+const checkpointMetadata = "checkpoint"
+
 // GitFlow returns the current state of the repository.
+// It is the Git implementation of both the Source and the Installer.
 type GitFlow interface {
-	LocalTag() (string, error)
-	LastTag() (string, error)
-	CheckoutTag(string) error
+	Source
+	Installer
+	Remote() (string, error)
+	Dirty() (bool, error)
 }
 
-// Repo represents a Git repository.
+// StepFunc is called each time the repository moves from a version to the next one.
+// Any error stops the update on the current version.
+type StepFunc func(from, to string) error
+
+// Repo represents a Git repository, or any versioned artifact with a Source and an Installer.
 type Repo struct {
 	git           GitFlow
 	diff          semver.Relationship
 	local, remote string
+	ahead         int
 	upStrategy    uint8
+	steps         []string
+	stepHooks     []StepFunc
+	hooks         [4][]Hook
+	path          string
+	migrationDir  string
+	migrationLog  string
+	checkedAt     time.Time
+	lastErr       error
+	name          string
+	events        *broker
+	deferred      string
+	stats         metrics
+	audit         *AuditLog
+	logger        Logger
+	notifiers     []Notifier
+	notified      map[string]bool
+	source        Source
+	installer     Installer
 }
 
 // UpdateStrategy represents the update mode.
 type UpdateStrategy struct {
-	until [4]uint8
+	until       [4]uint8
+	stepwise    bool
+	checkpoints map[string]bool
+	windows     []Window
+	rollout     *Rollout
 	// soon, we will also manage retryLater.
 }
 
@@ -68,7 +110,7 @@ func NewRepo(path string) (*Repo, error) {
 	if err != nil {
 		return nil, err
 	}
-	return &Repo{git: git}, nil
+	return &Repo{git: git, path: strings.TrimSpace(path)}, nil
 }
 
 // AddStrategy starts a new Git repository.
@@ -85,62 +127,364 @@ func (s *UpdateStrategy) AddStrategy(version, action uint8) (err error) {
 	return
 }
 
+// AddCheckpoint marks the given tag as a checkpoint, a stepwise update always stops on it.
+// A tag can also be marked as checkpoint with its build metadata, like v1.4.0+checkpoint.
+func (s *UpdateStrategy) AddCheckpoint(tag string) error {
+	if _, err := semver.Parse(tag); err != nil {
+		return err
+	}
+	if s.checkpoints == nil {
+		s.checkpoints = make(map[string]bool)
+	}
+	s.checkpoints[strings.TrimSpace(tag)] = true
+	return nil
+}
+
+// SetStepwise enables or disables the stepwise mode.
+// In this mode, an update goes through each eligible version instead of jumping to the latest.
+func (s *UpdateStrategy) SetStepwise(on bool) {
+	s.stepwise = on
+}
+
+// AddStepHook adds a function to call each time the repository moves from a version to the next one.
+func (r *Repo) AddStepHook(fn StepFunc) {
+	r.stepHooks = append(r.stepHooks, fn)
+}
+
+// Steps returns the versions crossed by the last update, starting with the version before it.
+func (r *Repo) Steps() []string {
+	return r.steps
+}
+
 // InDemand returns true if the Git repository needs to be updated because it is not on the latest tag.
 func (r *Repo) InDemand(s UpdateStrategy) bool {
-	var err error
+	r.stats.checks++
+	r.publish(CheckStarted{r.eventInfo()})
+	if r.local == "" {
+		// The hook receives the current version, even on the first check.
+		if local, ahead, err := r.localVersion(); err == nil {
+			r.local, r.ahead = local, ahead
+		}
+	}
+	if err := r.runHooks(BeforeCheck, r.local, ""); err != nil {
+		r.publish(CheckFailed{r.eventInfo(), err})
+		r.recordCheck(false, err)
+		r.info("check skipped by hook", "hook", hookNames[BeforeCheck], "error", err)
+		r.fail(r.local, "", err)
+		return false
+	}
+	ok, err := r.check(s)
+	r.checkedAt, r.lastErr = time.Now(), nil
+	r.recordCheck(ok, err)
+	if err != nil {
+		r.info("check failed", "local", r.local, "latest", r.remote, "error", err)
+		r.publish(CheckFailed{r.eventInfo(), err})
+		r.fail(r.local, r.remote, err)
+	} else if ok {
+		r.publish(UpdateAvailable{r.eventInfo(), r.local, r.remote, r.diff})
+		r.notify(NotifyAvailable, r.local, r.remote, nil)
+	}
+	return ok
+}
+
+// Update returns an error if it can not to update Git repository with the latest tag.
+// In stepwise mode, it goes through each eligible version and stops on the first checkpoint.
+func (r *Repo) Update(s UpdateStrategy) error {
+	return r.update(s, "")
+}
+
+// update updates the repository with the strategy. Once forced by an actor, it neither demands authorisation
+// to the user nor waits for a maintenance window.
+func (r *Repo) update(s UpdateStrategy, forcedBy string) error {
+	if !r.InDemand(s) {
+		return ErrNoUpdate
+	}
+	return r.updateChecked(s, forcedBy)
+}
+
+// updateChecked updates the repository on the version found by its last check.
+func (r *Repo) updateChecked(s UpdateStrategy, forcedBy string) (err error) {
+	old, path, actor, forced := r.local, []string{r.remote}, r.actor(forcedBy), forcedBy != ""
+	defer func() {
+		if err != nil && err != ErrDeferred {
+			r.record(AuditRecord{
+				Event: AuditUpdate, Old: old, New: path[len(path)-1], Actor: actor, Outcome: OutcomeFailed, Err: err.Error(),
+			})
+			r.info("update failed", "from", old, "to", path[len(path)-1], "local", r.local, "error", err)
+			r.notify(NotifyFailed, old, path[len(path)-1], err)
+			r.publish(UpdateFailed{r.eventInfo(), old, path[len(path)-1], err})
+			r.fail(old, path[len(path)-1], err)
+		}
+	}()
+	if s.stepwise {
+		if path, err = r.stepPath(s); err != nil {
+			path = []string{r.remote}
+			return
+		}
+	}
+	// Outside the maintenance windows, the automatic update is queued until the next one.
+	if r.upStrategy == Auto && !forced && !s.inWindow(timeNow()) {
+		r.deferred = path[len(path)-1]
+		r.publish(UpdateDeferred{r.eventInfo(), old, r.deferred})
+		r.record(AuditRecord{Event: AuditUpdate, Old: old, New: r.deferred, Actor: actor, Outcome: OutcomeDeferred})
+		r.info("update deferred until the next maintenance window", "from", old, "to", r.deferred)
+		return ErrDeferred
+	}
+	r.deferred = ""
+	// Manual update required, demands authorisation to user
+	if r.upStrategy == Manual && !forced {
+		// Display a message in order to inform about the available update.
+		fmt.Printf("You are currently on the '%v', a new version is available.\n", r.local)
+		if len(path) > 1 {
+			fmt.Printf("The update goes through the versions: %v.\n", strings.Join(path, ", "))
+		}
+		if rn, ok := r.versions().(ReleaseNotes); ok {
+			if notes, err := rn.Notes(path[len(path)-1]); err == nil && strings.TrimSpace(notes) != "" {
+				fmt.Printf("Release notes of '%v':\n%v\n", path[len(path)-1], strings.TrimSpace(notes))
+			}
+		}
+		fmt.Printf("Do you want to update and move on '%v'?\n", path[len(path)-1])
+		ok := confirmUpdate()
+		answer := AuditRecord{Event: AuditConfirm, Old: old, New: path[len(path)-1], Actor: actor, Outcome: OutcomeAccepted}
+		if !ok {
+			answer.Outcome = OutcomeDeclined
+		}
+		r.record(answer)
+		if !ok {
+			r.info("update declined", "from", old, "to", path[len(path)-1], "actor", actor)
+			return nil
+		}
+	}
+	// A failing hook cancels the update.
+	if err = r.runHooks(BeforeUpdate, old, path[len(path)-1]); err != nil {
+		return
+	}
+	if err = r.apply(path); err != nil {
+		return
+	}
+	if err = r.runHooks(AfterUpdate, old, r.local); err != nil {
+		return
+	}
+	r.stats.updates++
+	r.record(AuditRecord{Event: AuditUpdate, Old: old, New: r.local, Actor: actor, Outcome: OutcomeOK})
+	r.info("update applied", "from", old, "to", r.local, "steps", strings.Join(path, ","), "actor", actor)
+	r.notify(NotifyApplied, old, r.local, nil)
+	r.publish(UpdateApplied{r.eventInfo(), old, r.local})
+	return
+}
+
+// Rollback moves back the repository on the version it had before the last update.
+// The migrations applied by the update are not reverted.
+func (r *Repo) Rollback() error {
+	return r.rollback(currentUser())
+}
+
+// rollback moves back the repository on its previous version, as requested by the actor.
+func (r *Repo) rollback(actor string) (err error) {
+	if len(r.steps) < 2 {
+		return ErrNoRollback
+	}
+	from, to := r.local, r.steps[0]
+	rec := AuditRecord{Event: AuditRollback, Old: from, New: to, Actor: actor}
+	defer func() {
+		rec.Outcome, rec.Err = outcome(OutcomeOK, err)
+		r.record(rec)
+	}()
+	if err = r.checkout(to); err != nil {
+		r.fail(from, to, err)
+		return
+	}
+	r.local, r.ahead, r.steps = to, 0, nil
+	r.stats.rollbacks++
+	r.info("rolled back", "from", from, "to", to)
+	r.publish(RolledBack{r.eventInfo(), from, to})
+	return
+}
+
+// inherit carries over the state of the previous instance of the repository.
+func (r *Repo) inherit(prev *Repo) {
+	r.diff, r.local, r.remote, r.ahead = prev.diff, prev.local, prev.remote, prev.ahead
+	r.steps, r.deferred = prev.steps, prev.deferred
+	r.checkedAt, r.lastErr = prev.checkedAt, prev.lastErr
+	r.stats, r.notified, r.events = prev.stats, prev.notified, prev.events
+}
+
+// refresh forgets the versions of the last check, the next one gets them again from the repository.
+func (r *Repo) refresh() {
+	r.local, r.remote = "", ""
+}
+
+// check returns true if the repository has to be updated, according to the given strategy.
+func (r *Repo) check(s UpdateStrategy) (ok bool, err error) {
 	// Gets local version
 	if r.local == "" {
-		if r.local, err = r.git.LocalTag(); err != nil {
-			return false
+		if r.local, r.ahead, err = r.localVersion(); err != nil {
+			return
 		}
 	}
 	// Gets latest remote version
 	if r.remote == "" {
-		if r.remote, err = r.git.LastTag(); err != nil {
-			return false
+		start := time.Now()
+		r.remote, err = r.versions().LastTag()
+		r.stats.fetch.observe(start)
+		if err != nil {
+			return
 		}
 	}
 	// Gets differences between local and remote tags
 	if r.diff, err = semver.Compare(r.local, r.remote); err != nil {
-		return false
+		return
 	}
 	// Defines strategy to use by type of difference: major strategy by passing minor, etc.
-	if r.diff.Major < 0 {
-		if r.upStrategy = s.getStrategy(MajorVersion); r.upStrategy > Noop {
-			return true
+	if r.upStrategy, err = r.eligible(s, r.diff, r.remote); err != nil {
+		return
+	}
+	if r.upStrategy == Noop && s.stepwise {
+		// The latest version is not eligible, but a stepwise update can move on an intermediate one.
+		if path, err := r.stepPath(s); err == nil {
+			diff, _ := semver.Compare(r.local, path[len(path)-1])
+			r.upStrategy = s.action(diff)
 		}
-	} else if r.diff.Minor < 0 {
-		if r.upStrategy = s.getStrategy(MinorVersion); r.upStrategy > Noop {
-			return true
+	}
+	if r.upStrategy == Auto && r.ahead > 0 {
+		// Never moves automatically over the local commits of a developer.
+		r.upStrategy = Manual
+		r.info("automatic update disabled by local commits", "local", r.local, "ahead", r.ahead)
+	}
+	change := "none"
+	if kind := changeKind(r.diff); kind >= 0 {
+		change = kindNames[kind]
+	}
+	r.info("update decision", "local", r.local, "latest", r.remote, "change", change, "action", actionNames[r.upStrategy])
+	return r.upStrategy > Noop, nil
+}
+
+// apply checkouts in order each version of the path on the local repository.
+// After each of them, it runs the migrations and the step hooks.
+func (r *Repo) apply(path []string) (err error) {
+	r.steps = []string{r.local}
+	for _, tag := range path {
+		if err = r.checkout(tag); err != nil {
+			return
 		}
-	} else if r.diff.Patch < 0 {
-		if r.upStrategy = s.getStrategy(PatchVersion); r.upStrategy > Noop {
-			return true
+		from := r.local
+		r.local, r.ahead = tag, 0
+		r.steps = append(r.steps, tag)
+		if err = r.migrate(from, tag); err != nil {
+			return
 		}
-	} else if r.diff.PreRelease != "" {
-		if r.upStrategy = s.getStrategy(PreReleaseVersion); r.upStrategy > Noop {
-			return true
+		for _, fn := range r.stepHooks {
+			if err = fn(from, tag); err != nil {
+				return
+			}
 		}
 	}
-	return false
+	return
 }
 
-// Update returns an error if it can not to update Git repository with the latest tag.
-func (r *Repo) Update(s UpdateStrategy) error {
-	if !r.InDemand(s) {
-		return errors.New(errMsgInDemand)
+// checkout moves the local repository on the tag and measures the duration of it.
+func (r *Repo) checkout(tag string) error {
+	defer r.stats.checkout.observe(time.Now())
+	if f, ok := r.git.(tagFetcher); ok && r.source != nil {
+		// The versions do not come from the Git remote, the tag may be unknown by the local repository.
+		if err := f.FetchTag(tag); err != nil {
+			return err
+		}
 	}
-	// Manual update required, demands authorisation to user
-	if r.upStrategy == Manual {
-		// Display a message in order to inform about the available update.
-		fmt.Printf("You are currently on the '%v', a new version is available.\n", r.local)
-		fmt.Printf("Do you want to update and move on '%v'?\n", r.remote)
-		if !confirmUpdate() {
-			return nil
+	return r.installs().CheckoutTag(tag)
+}
+
+// stepPath returns in order the eligible versions between the local version and the latest one.
+// It stops on the first checkpoint. Pre-releases are only used if the latest version is one of them.
+func (r *Repo) stepPath(s UpdateStrategy) (path []string, err error) {
+	var tags []string
+	if tags, err = r.versions().Tags(); err != nil {
+		return
+	}
+	var lv, rv semver.Version
+	if lv, err = semver.Parse(r.local); err != nil {
+		return
+	}
+	if rv, err = semver.Parse(r.remote); err != nil {
+		return
+	}
+	cur := r.local
+	for _, tag := range semver.Sort(tags) {
+		v, _ := semver.Parse(tag)
+		if !lv.Less(v) || rv.Less(v) || (v.PreRelease != "" && tag != r.remote) {
+			continue
+		}
+		diff, _ := semver.Compare(cur, tag)
+		var action uint8
+		if action, err = r.eligible(s, diff, tag); err != nil {
+			return
+		}
+		if action == Noop {
+			continue
+		}
+		cur = tag
+		if path = append(path, tag); s.isCheckpoint(tag) {
+			break
+		}
+	}
+	if len(path) == 0 {
+		err = ErrNoUpdate
+	}
+	return
+}
+
+// eligible returns the action to perform to move on the tag with this difference.
+// With a staged rollout, the update is only performed once the host is selected for the tag.
+func (r *Repo) eligible(s UpdateStrategy, diff semver.Relationship, tag string) (uint8, error) {
+	action := s.action(diff)
+	if action == Noop || s.rollout == nil {
+		return action, nil
+	}
+	date, err := r.versions().TagDate(tag)
+	if err != nil {
+		return Noop, err
+	}
+	if !s.rollout.Allows(changeKind(diff), tag, timeNow().Sub(date)) {
+		return Noop, nil
+	}
+	return action, nil
+}
+
+// action returns the action to perform for this difference between the local and the remote versions.
+func (s *UpdateStrategy) action(diff semver.Relationship) uint8 {
+	return s.getStrategy(changeKind(diff))
+}
+
+// changeKind returns the type of version (major, minor, etc.) changed by this difference, -1 if there is none.
+func changeKind(diff semver.Relationship) int8 {
+	switch {
+	case diff.Major < 0:
+		return MajorVersion
+	case diff.Minor < 0:
+		return MinorVersion
+	case diff.Patch < 0:
+		return PatchVersion
+	case diff.PreRelease != "":
+		return PreReleaseVersion
+	}
+	return -1
+}
+
+// isCheckpoint returns true if the tag is marked as checkpoint by configuration or by its build metadata.
+func (s *UpdateStrategy) isCheckpoint(tag string) bool {
+	if s.checkpoints[tag] {
+		return true
+	}
+	v, err := semver.Parse(tag)
+	if err != nil {
+		return false
+	}
+	for _, id := range strings.Split(v.Build, ".") {
+		if id == checkpointMetadata {
+			return true
 		}
 	}
-	// Checkout it on the local repository
-	return r.git.CheckoutTag(r.remote)
+	return false
 }
 
 // getStrategy returns for the type of version (major, minor, etc.), the action to perform.
@@ -5,12 +5,15 @@ import (
 	"github.com/rvflash/gitup/internal/gitflow"
 	"io/ioutil"
 	"os"
+	"strings"
 	"testing"
+	"time"
 )
 
 const (
 	errMsgFake = "fake error"
 	errValue   = "error"
+	remoteURL  = "https://github.com/rvflash/gitup.git"
 )
 
 // FakeGitFlow implements GitFlow to order to mock *gitflow.Repo.
@@ -30,14 +33,49 @@ var repoTests = []struct {
 	{&FakeGitFlow{true, false, false, "", "v1.0.0"}, UpdateStrategy{}, false, ""},
 	{&FakeGitFlow{false, false, true, "v1.0.0", "v1.0.0"}, UpdateStrategy{}, false, ""},
 	{&FakeGitFlow{false, false, false, "v1.0", "v1.0.0"}, UpdateStrategy{}, false, ""},
-	{&FakeGitFlow{false, false, true, "v1.0.0", "v1.1.0"}, UpdateStrategy{[4]uint8{Auto}}, true, ""},
+	{&FakeGitFlow{false, false, true, "v1.0.0", "v1.1.0"}, UpdateStrategy{until: [4]uint8{Auto}}, true, ""},
 	{&FakeGitFlow{false, false, false, "v1.0.0", "v1.0.0"}, UpdateStrategy{}, false, ""}, // Valid entries, fails
 	{&FakeGitFlow{false, false, false, "v1.0.0", "v2.0.0"}, UpdateStrategy{}, false, ""},
 	{&FakeGitFlow{false, false, false, "v2.0.0", "v1.0.0"}, UpdateStrategy{}, false, ""},
-	{&FakeGitFlow{false, false, false, "v1.0.0", "v1.0.1"}, UpdateStrategy{[4]uint8{Auto}}, true, ""}, // Valid entries, successful
-	{&FakeGitFlow{false, false, false, "v1.0.0-alpha", "v1.0.0-beta"}, UpdateStrategy{[4]uint8{Auto}}, true, ""},
-	{&FakeGitFlow{false, false, false, "v1.0.0", "v2.0.0"}, UpdateStrategy{[4]uint8{Manual}}, true, "y"},
-	{&FakeGitFlow{false, false, false, "v1.0.0", "v2.0.0"}, UpdateStrategy{[4]uint8{Manual}}, true, "n"},
+	{&FakeGitFlow{false, false, false, "v1.0.0", "v1.0.1"}, UpdateStrategy{until: [4]uint8{Auto}}, true, ""}, // Valid entries, successful
+	{&FakeGitFlow{false, false, false, "v1.0.0-alpha", "v1.0.0-beta"}, UpdateStrategy{until: [4]uint8{Auto}}, true, ""},
+	{&FakeGitFlow{false, false, false, "v1.0.0", "v2.0.0"}, UpdateStrategy{until: [4]uint8{Manual}}, true, "y"},
+	{&FakeGitFlow{false, false, false, "v1.0.0", "v2.0.0"}, UpdateStrategy{until: [4]uint8{Manual}}, true, "n"},
+}
+
+// FakeStepFlow extends FakeGitFlow to list various tags and record each checkout.
+type FakeStepFlow struct {
+	FakeGitFlow
+	tags, checkouts []string
+	dates           map[string]time.Time
+}
+
+var stepTests = []struct {
+	tags        []string // input
+	checkpoints []string
+	strategy    [4]uint8
+	steps       []string // expected result
+}{
+	{
+		[]string{"v1.3.0", "v1.4.0", "v1.5.0", "v1.6.0"}, nil, [4]uint8{Auto},
+		[]string{"v1.3.0", "v1.4.0", "v1.5.0", "v1.6.0"},
+	},
+	{
+		[]string{"v1.6.0", "v1.4.0", "v1.3.0", "v1.5.0", "v1.5.1", "v1.5.0-beta"}, nil, [4]uint8{Auto},
+		[]string{"v1.3.0", "v1.4.0", "v1.5.0", "v1.5.1", "v1.6.0"},
+	},
+	{
+		[]string{"v1.3.0", "v1.4.0", "v1.5.0", "v1.6.0"}, []string{"v1.5.0"}, [4]uint8{Auto},
+		[]string{"v1.3.0", "v1.4.0", "v1.5.0"},
+	},
+	{
+		[]string{"v1.3.0", "v1.4.0+checkpoint", "v1.5.0", "v1.6.0"}, nil, [4]uint8{Auto},
+		[]string{"v1.3.0", "v1.4.0+checkpoint"},
+	},
+	{
+		[]string{"v1.3.0", "v1.3.1", "v1.4.0", "v1.4.1", "v1.6.0"}, nil, [4]uint8{Noop, Noop, Auto},
+		[]string{"v1.3.0", "v1.3.1"},
+	},
 }
 
 var confirmTests = []struct {
@@ -107,6 +145,29 @@ func (r FakeGitFlow) LastTag() (string, error) {
 	return r.remoteTag, nil
 }
 
+// Tags mocks the gitflow's method Tags() on FakeGitFlow struct.
+func (r FakeGitFlow) Tags() ([]string, error) {
+	if r.remoteError {
+		return nil, errors.New(errMsgFake)
+	}
+	return []string{r.localTag, r.remoteTag}, nil
+}
+
+// TagDate mocks the gitflow's method TagDate() on FakeGitFlow struct.
+func (r FakeGitFlow) TagDate(string) (time.Time, error) {
+	return time.Time{}, nil
+}
+
+// Remote mocks the gitflow's method Remote() on FakeGitFlow struct.
+func (r FakeGitFlow) Remote() (string, error) {
+	return remoteURL, nil
+}
+
+// Dirty mocks the gitflow's method Dirty() on FakeGitFlow struct.
+func (r FakeGitFlow) Dirty() (bool, error) {
+	return false, nil
+}
+
 // CheckoutTag mocks the gitflow's method CheckoutTag() on FakeGitFlow struct.
 func (r FakeGitFlow) CheckoutTag(string) error {
 	if r.checkoutError {
@@ -115,6 +176,22 @@ func (r FakeGitFlow) CheckoutTag(string) error {
 	return nil
 }
 
+// Tags mocks the gitflow's method Tags() on FakeStepFlow struct.
+func (r *FakeStepFlow) Tags() ([]string, error) {
+	return r.tags, nil
+}
+
+// TagDate mocks the gitflow's method TagDate() on FakeStepFlow struct.
+func (r *FakeStepFlow) TagDate(tag string) (time.Time, error) {
+	return r.dates[tag], nil
+}
+
+// CheckoutTag mocks the gitflow's method CheckoutTag() on FakeStepFlow struct.
+func (r *FakeStepFlow) CheckoutTag(tag string) error {
+	r.checkouts = append(r.checkouts, tag)
+	return nil
+}
+
 // fakeStdin returns a temporary file with required content to mock stdin.
 // os.Stdin as a file implements *os.File interface.
 func fakeStdin(str string) (stdin *os.File, err error) {
@@ -187,6 +264,74 @@ func TestRepo_Update(t *testing.T) {
 	}
 }
 
+// TestRepo_Update_Stepwise tests Update method in stepwise mode with various tags and checkpoints.
+func TestRepo_Update_Stepwise(t *testing.T) {
+	for _, st := range stepTests {
+		git := &FakeStepFlow{FakeGitFlow: FakeGitFlow{localTag: "v1.3.0", remoteTag: "v1.6.0"}, tags: st.tags}
+		s := UpdateStrategy{until: st.strategy}
+		s.SetStepwise(true)
+		for _, tag := range st.checkpoints {
+			if err := s.AddCheckpoint(tag); err != nil {
+				t.Fatalf("Expected no error with checkpoint %v, received: %v", tag, err)
+			}
+		}
+		var hooks []string
+		r := &Repo{git: git}
+		r.AddStepHook(func(from, to string) error {
+			hooks = append(hooks, from+">"+to)
+			return nil
+		})
+		if err := r.Update(s); err != nil {
+			t.Errorf("Expected no error with tags %v, received: %v", st.tags, err)
+		}
+		if path := strings.Join(r.Steps(), ","); path != strings.Join(st.steps, ",") {
+			t.Errorf("Expected path %v with tags %v, received: %v", st.steps, st.tags, r.Steps())
+		}
+		if checkouts := strings.Join(git.checkouts, ","); checkouts != strings.Join(st.steps[1:], ",") {
+			t.Errorf("Expected checkouts %v with tags %v, received: %v", st.steps[1:], st.tags, git.checkouts)
+		}
+		if len(hooks) != len(st.steps)-1 {
+			t.Errorf("Expected %d step hooks with tags %v, received: %v", len(st.steps)-1, st.tags, hooks)
+		}
+	}
+}
+
+// TestRepo_AddStepHook tests that a failing step hook stops the update on the current version.
+func TestRepo_AddStepHook(t *testing.T) {
+	git := &FakeStepFlow{
+		FakeGitFlow: FakeGitFlow{localTag: "v1.3.0", remoteTag: "v1.5.0"},
+		tags:        []string{"v1.3.0", "v1.4.0", "v1.5.0"},
+	}
+	s := UpdateStrategy{until: [4]uint8{Auto}}
+	s.SetStepwise(true)
+	r := &Repo{git: git}
+	r.AddStepHook(func(from, to string) error {
+		return errors.New(errMsgFake)
+	})
+	if err := r.Update(s); err == nil {
+		t.Error("Expected error with a failing step hook")
+	}
+	if steps := strings.Join(r.Steps(), ","); steps != "v1.3.0,v1.4.0" {
+		t.Errorf("Expected update stopped on v1.4.0, received: %v", steps)
+	}
+}
+
+// TestAddCheckpoint tests AddCheckpoint method with valid or invalid tags.
+func TestAddCheckpoint(t *testing.T) {
+	s := new(UpdateStrategy)
+	if err := s.AddCheckpoint("1.2"); err == nil {
+		t.Error("Expected error with invalid checkpoint")
+	}
+	if err := s.AddCheckpoint(" v1.2.0"); err != nil {
+		t.Errorf("Expected no error with valid checkpoint, received: %v", err)
+	}
+	for tag, ok := range map[string]bool{"v1.2.0": true, "v1.3.0+checkpoint": true, "v1.3.0+build.checkpoint": true, "v1.4.0": false} {
+		if s.isCheckpoint(tag) != ok {
+			t.Errorf("Expected checkpoint %t for %v", ok, tag)
+		}
+	}
+}
+
 // TestAddStrategy tests AddStrategy method with various values.
 func TestAddStrategy(t *testing.T) {
 	s := new(UpdateStrategy)
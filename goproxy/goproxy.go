@@ -0,0 +1,278 @@
+// Package goproxy provides the versions of a Go module published through module proxies,
+// with the GOPROXY protocol: /@v/list, /@v/<version>.info and /@latest.
+//
+// Like the go command, the proxies are tried in order: after a comma, the next one is only used
+// if the module is not found (404 or 410), after a pipe, on any error. The keyword "direct" uses
+// the fallback source, like the Git repository itself, and "off" disallows any other source.
+// The modules matching the private patterns never use the proxies, like with GOPRIVATE.
+package goproxy
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/rvflash/gitup/internal/semver"
+)
+
+// DefaultProxy is the list of proxies used without GOPROXY.
+const DefaultProxy = "https://proxy.golang.org,direct"
+
+// Keywords of the list of proxies.
+const (
+	direct = "direct"
+	off    = "off"
+)
+
+// Error messages.
+const (
+	errMsgModule   = "module path is undefined"
+	errMsgNoProxy  = "no proxy available for the module"
+	errMsgOff      = "module lookup disabled by GOPROXY=off"
+	errMsgNoDirect = "no direct source for the module"
+	errMsgNoTag    = "no version available for the module"
+)
+
+// errNotFound is returned when a proxy does not know the module or the version.
+var errNotFound = errors.New("module or version not found on the proxy")
+
+// Source provides the available versions, like the Git repository used for "direct".
+type Source interface {
+	LastTag() (string, error)
+	Tags() ([]string, error)
+	TagDate(string) (time.Time, error)
+}
+
+// Info is the metadata of a version served by a proxy.
+type Info struct {
+	Version string
+	Time    time.Time
+}
+
+// proxy is an entry of the list of proxies.
+type proxy struct {
+	url string
+	// fallback is true if the next proxy is used on any error, false if only the module is not found.
+	fallback bool
+}
+
+// Proxy is a source of the versions of a Go module.
+type Proxy struct {
+	// Client is the HTTP client used to query the proxies, http.DefaultClient if nil.
+	Client  *http.Client
+	module  string
+	proxies []proxy
+	private []string
+	direct  Source
+}
+
+// New returns a source of the versions of the module, with the list of proxies like GOPROXY
+// and the comma-separated list of glob patterns of the private modules like GOPRIVATE.
+// Empty, they are read from the environment. The direct source is optional.
+func New(module, proxies, private string, direct Source) (*Proxy, error) {
+	if module = strings.TrimSpace(module); module == "" {
+		return nil, errors.New(errMsgModule)
+	}
+	if proxies == "" {
+		if proxies = os.Getenv("GOPROXY"); proxies == "" {
+			proxies = DefaultProxy
+		}
+	}
+	if private == "" {
+		if private = os.Getenv("GONOPROXY"); private == "" {
+			private = os.Getenv("GOPRIVATE")
+		}
+	}
+	p := &Proxy{module: module, proxies: parseList(proxies), direct: direct}
+	for _, pattern := range strings.Split(private, ",") {
+		if pattern = strings.TrimSpace(pattern); pattern != "" {
+			p.private = append(p.private, pattern)
+		}
+	}
+	return p, nil
+}
+
+// LastTag returns the latest version listed by the proxy, or else the one of its latest endpoint.
+func (p *Proxy) LastTag() (string, error) {
+	tags, err := p.Tags()
+	if err != nil {
+		return "", err
+	}
+	if sorted := semver.Sort(tags); len(sorted) > 0 {
+		return sorted[len(sorted)-1], nil
+	}
+	var tag string
+	err = p.lookup(func(base string) error {
+		info, err := p.info(base, "@latest")
+		tag = info.Version
+		return err
+	}, func(s Source) (err error) {
+		tag, err = s.LastTag()
+		return
+	})
+	if err == nil && tag == "" {
+		err = errors.New(errMsgNoTag)
+	}
+	return tag, err
+}
+
+// Tags returns the versions listed by the proxy.
+func (p *Proxy) Tags() (tags []string, err error) {
+	err = p.lookup(func(base string) error {
+		buf, err := p.get(base + "/@v/list")
+		tags = strings.Fields(string(buf))
+		return err
+	}, func(s Source) (err error) {
+		tags, err = s.Tags()
+		return
+	})
+	return
+}
+
+// TagDate returns the publication date of the version.
+func (p *Proxy) TagDate(tag string) (date time.Time, err error) {
+	err = p.lookup(func(base string) error {
+		v, err := escape(tag)
+		if err != nil {
+			return err
+		}
+		info, err := p.info(base, "@v/"+v+".info")
+		date = info.Time
+		return err
+	}, func(s Source) (err error) {
+		date, err = s.TagDate(tag)
+		return
+	})
+	return
+}
+
+// lookup tries each proxy in order with the query, then the direct source.
+func (p *Proxy) lookup(query func(base string) error, fromDirect func(s Source) error) error {
+	mod, err := escape(p.module)
+	if err != nil {
+		return err
+	}
+	list := p.proxies
+	if p.isPrivate() {
+		list = []proxy{{url: direct}}
+	}
+	err = errors.New(errMsgNoProxy)
+	for _, px := range list {
+		switch px.url {
+		case off:
+			return errors.New(errMsgOff)
+		case direct:
+			if p.direct == nil {
+				return errors.New(errMsgNoDirect)
+			}
+			return fromDirect(p.direct)
+		}
+		if err = query(strings.TrimSuffix(px.url, "/") + "/" + mod); err == nil {
+			return nil
+		}
+		if err != errNotFound && !px.fallback {
+			return err
+		}
+	}
+	return err
+}
+
+// info returns the metadata of a version at this endpoint of the module.
+func (p *Proxy) info(base, endpoint string) (info Info, err error) {
+	var buf []byte
+	if buf, err = p.get(base + "/" + endpoint); err == nil {
+		err = json.Unmarshal(buf, &info)
+	}
+	return
+}
+
+// get returns the content at the URL, served over HTTP or read in a directory with a file URL.
+func (p *Proxy) get(rawURL string) ([]byte, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, err
+	}
+	if u.Scheme == "file" {
+		buf, err := ioutil.ReadFile(filepath.FromSlash(u.Path))
+		if os.IsNotExist(err) {
+			return nil, errNotFound
+		}
+		return buf, err
+	}
+	client := p.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Get(rawURL)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	switch resp.StatusCode {
+	case http.StatusOK:
+		return ioutil.ReadAll(resp.Body)
+	case http.StatusNotFound, http.StatusGone:
+		return nil, errNotFound
+	}
+	return nil, fmt.Errorf("%s: %s", rawURL, resp.Status)
+}
+
+// isPrivate returns true if the module matches one of the private patterns.
+// Like GOPRIVATE, a pattern matches a prefix of the module path by path elements.
+func (p *Proxy) isPrivate() bool {
+	for _, pattern := range p.private {
+		n := strings.Count(pattern, "/") + 1
+		prefix := strings.SplitN(p.module, "/", n+1)
+		if len(prefix) < n {
+			continue
+		}
+		if ok, _ := path.Match(pattern, strings.Join(prefix[:n], "/")); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// parseList returns the proxies of a list like GOPROXY.
+func parseList(list string) (proxies []proxy) {
+	for list != "" {
+		i := strings.IndexAny(list, ",|")
+		entry, sep := list, byte(0)
+		if i > -1 {
+			entry, sep, list = list[:i], list[i], list[i+1:]
+		} else {
+			list = ""
+		}
+		if entry = strings.TrimSpace(entry); entry != "" {
+			proxies = append(proxies, proxy{url: entry, fallback: sep == '|'})
+		}
+	}
+	return
+}
+
+// escape returns the module path or the version as used in the URLs of a proxy:
+// each upper-case letter is replaced by an exclamation mark followed by its lower-case.
+func escape(s string) (string, error) {
+	var b strings.Builder
+	for _, r := range s {
+		switch {
+		case r == '!' || r >= unicode.MaxASCII:
+			return "", fmt.Errorf("invalid character %q in %q", r, s)
+		case unicode.IsUpper(r):
+			b.WriteByte('!')
+			b.WriteRune(unicode.ToLower(r))
+		default:
+			b.WriteRune(r)
+		}
+	}
+	return b.String(), nil
+}
@@ -0,0 +1,130 @@
+package goproxy_test
+
+import (
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/rvflash/gitup/goproxy"
+)
+
+const moduleTest = "example.com/Corp/app"
+
+// FakeSource is the direct source of the versions.
+type FakeSource struct{}
+
+// LastTag implements the goproxy.Source interface.
+func (FakeSource) LastTag() (string, error) { return "v0.9.0", nil }
+
+// Tags implements the goproxy.Source interface.
+func (FakeSource) Tags() ([]string, error) { return []string{"v0.9.0"}, nil }
+
+// TagDate implements the goproxy.Source interface.
+func (FakeSource) TagDate(string) (time.Time, error) { return time.Time{}, errors.New("no date") }
+
+// newProxy returns a module proxy serving the module, or failing with this status code.
+func newProxy(code int, list string) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if code != http.StatusOK {
+			w.WriteHeader(code)
+			return
+		}
+		switch r.URL.Path {
+		case "/example.com/!corp/app/@v/list":
+			w.Write([]byte(list))
+		case "/example.com/!corp/app/@latest":
+			w.Write([]byte(`{"Version":"v0.0.0-20170601100000-5114f85","Time":"2017-06-01T10:00:00Z"}`))
+		case "/example.com/!corp/app/@v/v1.10.0.info":
+			w.Write([]byte(`{"Version":"v1.10.0","Time":"2017-06-02T10:00:00Z"}`))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+}
+
+// TestProxy_LastTag tests the latest version with various lists of proxies.
+func TestProxy_LastTag(t *testing.T) {
+	ok := newProxy(http.StatusOK, "v1.2.0\nv1.10.0\nv1.9.0\n")
+	defer ok.Close()
+	empty := newProxy(http.StatusOK, "")
+	defer empty.Close()
+	gone := newProxy(http.StatusGone, "")
+	defer gone.Close()
+	failing := newProxy(http.StatusInternalServerError, "")
+	defer failing.Close()
+
+	for _, lt := range []struct {
+		proxies, private string // input
+		tag              string // expected result
+		onErr            bool
+	}{
+		{proxies: ok.URL, tag: "v1.10.0"},
+		{proxies: empty.URL, tag: "v0.0.0-20170601100000-5114f85"},
+		{proxies: gone.URL + "," + ok.URL, tag: "v1.10.0"},
+		{proxies: gone.URL + ",direct", tag: "v0.9.0"},
+		{proxies: failing.URL + "," + ok.URL, onErr: true},
+		{proxies: failing.URL + "|" + ok.URL, tag: "v1.10.0"},
+		{proxies: gone.URL + ",off", onErr: true},
+		{proxies: gone.URL, onErr: true},
+		{proxies: ok.URL, private: "example.com/Corp", tag: "v0.9.0"},
+		{proxies: ok.URL, private: "*.org,example.com/*/app", tag: "v0.9.0"},
+		{proxies: ok.URL, private: "example.com/Corp/app/v2", tag: "v1.10.0"},
+	} {
+		p, err := goproxy.New(moduleTest, lt.proxies, lt.private, FakeSource{})
+		if err != nil {
+			t.Fatalf("Expected no error, received: %v", err)
+		}
+		if tag, err := p.LastTag(); (err != nil) != lt.onErr || tag != lt.tag {
+			t.Errorf("Expected %q (error: %t) with %v, received: %q, %v", lt.tag, lt.onErr, lt.proxies, tag, err)
+		}
+	}
+	if _, err := goproxy.New(" ", ok.URL, "", nil); err == nil {
+		t.Error("Expected error without module")
+	}
+	p, _ := goproxy.New(moduleTest, "direct", "", nil)
+	if _, err := p.LastTag(); err == nil {
+		t.Error("Expected error without direct source")
+	}
+}
+
+// TestProxy_TagDate tests the date of a version.
+func TestProxy_TagDate(t *testing.T) {
+	srv := newProxy(http.StatusOK, "v1.10.0")
+	defer srv.Close()
+
+	p, _ := goproxy.New(moduleTest, srv.URL, "", nil)
+	if date, err := p.TagDate("v1.10.0"); err != nil || !date.Equal(time.Date(2017, 6, 2, 10, 0, 0, 0, time.UTC)) {
+		t.Errorf("Expected date of the version, received: %v, %v", date, err)
+	}
+	if _, err := p.TagDate("v1.11.0"); err == nil {
+		t.Error("Expected error with unknown version")
+	}
+}
+
+// TestProxy_Tags tests a proxy read from a directory.
+func TestProxy_Tags(t *testing.T) {
+	dir, err := ioutil.TempDir(os.TempDir(), "goproxy")
+	if err != nil {
+		t.Fatalf("Unable to create directory, received: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	vdir := filepath.Join(dir, "example.com", "!corp", "app", "@v")
+	os.MkdirAll(vdir, 0755)
+	ioutil.WriteFile(filepath.Join(vdir, "list"), []byte("v1.0.0\nv1.1.0\n"), 0644)
+
+	p, _ := goproxy.New(moduleTest, "file://"+filepath.ToSlash(dir), "", nil)
+	if tags, err := p.Tags(); err != nil || strings.Join(tags, ",") != "v1.0.0,v1.1.0" {
+		t.Errorf("Expected versions of the directory, received: %v, %v", tags, err)
+	}
+	p, _ = goproxy.New("example.com/corp/api", "file://"+filepath.ToSlash(dir), "", nil)
+	if _, err := p.Tags(); err == nil {
+		t.Error("Expected error with unknown module")
+	}
+}
@@ -0,0 +1,118 @@
+package gitup
+
+import (
+	"errors"
+	"fmt"
+	"os"
+)
+
+// Hook points.
+const (
+	BeforeCheck  = iota // 0
+	BeforeUpdate        // 1
+	AfterUpdate         // 2
+	OnError             // 3
+)
+
+// Hook settings.
+const (
+	errMsgHookPoint = "unknown hook point"
+	envHookPoint    = "GITUP_HOOK"
+	envRepoPath     = "GITUP_REPO_PATH"
+	envError        = "GITUP_ERROR"
+)
+
+// hookNames gives for each hook point its name.
+var hookNames = [...]string{"before_check", "before_update", "after_update", "on_error"}
+
+// HookEvent describes the context in which a hook is run.
+// Path is the directory of the installed version, New is empty before the check and Err is only set on error.
+type HookEvent struct {
+	Point    uint8
+	Path     string
+	Old, New string
+	Err      error
+}
+
+// Hook is run on a hook point of the repository's lifecycle.
+type Hook interface {
+	Run(e HookEvent) error
+}
+
+// HookFunc is an adapter to use an ordinary function as Hook.
+type HookFunc func(e HookEvent) error
+
+// Run implements the Hook interface.
+func (f HookFunc) Run(e HookEvent) error {
+	return f(e)
+}
+
+// Command is a shell command used as Hook.
+// It runs in the directory of the installed version and receives the old and new versions
+// with the environment variables GITUP_OLD_VERSION and GITUP_NEW_VERSION.
+type Command string
+
+// Run implements the Hook interface.
+func (c Command) Run(e HookEvent) error {
+	cmd := execCommand("sh", "-c", string(c))
+	cmd.Dir = e.Path
+	if cmd.Env == nil {
+		cmd.Env = os.Environ()
+	}
+	cmd.Env = append(cmd.Env,
+		envHookPoint+"="+hookNames[e.Point],
+		envRepoPath+"="+e.Path,
+		envOldVersion+"="+e.Old,
+		envNewVersion+"="+e.New,
+	)
+	if e.Err != nil {
+		cmd.Env = append(cmd.Env, envError+"="+e.Err.Error())
+	}
+	return cmd.Run()
+}
+
+// String implements the fmt.Stringer interface.
+func (c Command) String() string {
+	return string(c)
+}
+
+// AddHook adds a hook to run on the given point of the lifecycle: BeforeCheck, BeforeUpdate, AfterUpdate or OnError.
+// A failing BeforeCheck hook skips the check, a failing BeforeUpdate hook cancels the update.
+func (r *Repo) AddHook(point uint8, h Hook) error {
+	if point > OnError {
+		return errors.New(errMsgHookPoint)
+	}
+	r.hooks[point] = append(r.hooks[point], h)
+	return nil
+}
+
+// runHooks runs in order the hooks of this point and stops on the first error.
+func (r *Repo) runHooks(point uint8, old, new string) error {
+	for _, h := range r.hooks[point] {
+		if err := r.runHook(h, HookEvent{Point: point, Path: r.workDir(), Old: old, New: new}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// runHook runs the hook with this event and records its result in the audit log.
+func (r *Repo) runHook(h Hook, e HookEvent) error {
+	err := h.Run(e)
+	rec := AuditRecord{Event: AuditHook, Old: e.Old, New: e.New, Hook: hookNames[e.Point]}
+	if s, ok := h.(fmt.Stringer); ok {
+		rec.Command = s.String()
+	}
+	rec.Outcome, rec.Err = outcome(OutcomeOK, err)
+	r.record(rec)
+	return err
+}
+
+// fail records the error and runs the OnError hooks with it. It deliberately ignores their errors.
+func (r *Repo) fail(old, new string, err error) {
+	r.lastErr = err
+	r.stats.failures++
+	for _, h := range r.hooks[OnError] {
+		r.runHook(h, HookEvent{Point: OnError, Path: r.workDir(), Old: old, New: new, Err: err})
+	}
+}
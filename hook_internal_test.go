@@ -0,0 +1,96 @@
+package gitup
+
+import (
+	"errors"
+	"os"
+	"os/exec"
+	"strings"
+	"testing"
+)
+
+var hookTests = []struct {
+	git      *FakeStepFlow
+	failOn   int    // input: hook point to fail, -1 for none
+	calls    string // expected result
+	checkout bool
+	onErr    bool
+}{
+	{
+		&FakeStepFlow{FakeGitFlow: FakeGitFlow{localTag: "v1.0.0", remoteTag: "v1.1.0"}}, -1,
+		"before_check:v1.0.0>,before_update:v1.0.0>v1.1.0,after_update:v1.0.0>v1.1.0", true, false,
+	},
+	{
+		&FakeStepFlow{FakeGitFlow: FakeGitFlow{localTag: "v1.0.0", remoteTag: "v1.1.0"}}, BeforeCheck,
+		"before_check:v1.0.0>,on_error:v1.0.0>", false, true,
+	},
+	{
+		&FakeStepFlow{FakeGitFlow: FakeGitFlow{localTag: "v1.0.0", remoteTag: "v1.1.0"}}, BeforeUpdate,
+		"before_check:v1.0.0>,before_update:v1.0.0>v1.1.0,on_error:v1.0.0>v1.1.0", false, true,
+	},
+	{
+		&FakeStepFlow{FakeGitFlow: FakeGitFlow{localTag: "v1.0.0", remoteTag: "v1.1.0"}}, AfterUpdate,
+		"before_check:v1.0.0>,before_update:v1.0.0>v1.1.0,after_update:v1.0.0>v1.1.0,on_error:v1.0.0>v1.1.0", true, true,
+	},
+	{
+		&FakeStepFlow{FakeGitFlow: FakeGitFlow{remoteError: true, localTag: "v1.0.0"}}, -1,
+		"before_check:v1.0.0>,on_error:v1.0.0>", false, true,
+	},
+}
+
+// TestRepo_AddHook tests AddHook method with valid or invalid hook points.
+func TestRepo_AddHook(t *testing.T) {
+	r := new(Repo)
+	for point := uint8(BeforeCheck); point <= OnError; point++ {
+		if err := r.AddHook(point, Command("true")); err != nil {
+			t.Errorf("Expected no error for the hook point %v, received: %v", point, err)
+		}
+	}
+	if err := r.AddHook(OnError+1, Command("true")); err == nil {
+		t.Error("Expected error with unknown hook point")
+	}
+}
+
+// TestRepo_Update_Hooks tests the hooks run by the Update method.
+func TestRepo_Update_Hooks(t *testing.T) {
+	for _, ht := range hookTests {
+		var calls []string
+		r := &Repo{git: ht.git}
+		for point := uint8(BeforeCheck); point <= OnError; point++ {
+			r.AddHook(point, HookFunc(func(e HookEvent) error {
+				calls = append(calls, hookNames[e.Point]+":"+e.Old+">"+e.New)
+				if int(e.Point) == ht.failOn {
+					return errors.New(errMsgFake)
+				}
+				if e.Point == OnError && e.Err == nil {
+					t.Error("Expected error on the hook point OnError")
+				}
+				return nil
+			}))
+		}
+		if err := r.Update(UpdateStrategy{until: [4]uint8{Auto}}); (err != nil) != ht.onErr {
+			t.Errorf("Expected error %t with hook point %v on failure, received: %v", ht.onErr, ht.failOn, err)
+		}
+		if c := strings.Join(calls, ","); c != ht.calls {
+			t.Errorf("Expected hook calls %v, received: %v", ht.calls, c)
+		}
+		if (len(ht.git.checkouts) > 0) != ht.checkout {
+			t.Errorf("Expected checkout %t with hook point %v on failure, received: %v", ht.checkout, ht.failOn, ht.git.checkouts)
+		}
+	}
+}
+
+// TestCommand_Run tests the run of a shell command as hook.
+func TestCommand_Run(t *testing.T) {
+	execCommand = fakeExecCommand
+
+	// Restore exec command behavior at the end of the test.
+	defer func() { execCommand = exec.Command }()
+
+	e := HookEvent{Point: AfterUpdate, Path: os.TempDir(), Old: "v1.0.0", New: "v1.1.0"}
+	if err := Command("make install").Run(e); err != nil {
+		t.Errorf("Expected no error, received: %v", err)
+	}
+	if err := Command("make " + failScript).Run(e); err == nil {
+		t.Error("Expected error with failing command")
+	}
+}
@@ -0,0 +1,121 @@
+package cron
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const errMsgExpression = "not a valid cron expression"
+
+// Schedule represents a cron expression with the five standard fields:
+// minute, hour, day of month, month and day of week.
+// @example */15 2-4 * * 1-5
+type Schedule struct {
+	minute, hour, dom, month, dow uint64
+	anyDom, anyDow                bool
+}
+
+// bounds of each field.
+var bounds = [5][2]int{{0, 59}, {0, 23}, {1, 31}, {1, 12}, {0, 7}}
+
+// Parse returns the schedule of the cron expression.
+func Parse(expr string) (*Schedule, error) {
+	fields := strings.Fields(expr)
+	if len(fields) != 5 {
+		return nil, errors.New(errMsgExpression)
+	}
+	var bits [5]uint64
+	for i, f := range fields {
+		var err error
+		if bits[i], err = parseField(f, bounds[i][0], bounds[i][1]); err != nil {
+			return nil, err
+		}
+	}
+	// Sunday is either 0 or 7.
+	if bits[4]&(1<<7) != 0 {
+		bits[4] |= 1
+	}
+	return &Schedule{
+		minute: bits[0],
+		hour:   bits[1],
+		dom:    bits[2],
+		month:  bits[3],
+		dow:    bits[4],
+		// As with the standard cron, a field starting with a star, like "*/2", is not a restriction.
+		anyDom: strings.HasPrefix(fields[2], "*"),
+		anyDow: strings.HasPrefix(fields[4], "*"),
+	}, nil
+}
+
+// Next returns the first time of the schedule after t, with a precision to the minute.
+// It returns a zero time if none matches within the next five years.
+func (s *Schedule) Next(t time.Time) time.Time {
+	// Works on the wall clock of the location, whose offset may not be a whole number of hours.
+	t = time.Date(t.Year(), t.Month(), t.Day(), t.Hour(), t.Minute(), 0, 0, t.Location()).Add(time.Minute)
+	for limit := t.AddDate(5, 0, 0); t.Before(limit); {
+		switch {
+		case !has(s.month, int(t.Month())):
+			t = time.Date(t.Year(), t.Month()+1, 1, 0, 0, 0, 0, t.Location())
+		case !s.matchDay(t):
+			t = time.Date(t.Year(), t.Month(), t.Day()+1, 0, 0, 0, 0, t.Location())
+		case !has(s.hour, t.Hour()):
+			t = time.Date(t.Year(), t.Month(), t.Day(), t.Hour()+1, 0, 0, 0, t.Location())
+		case !has(s.minute, t.Minute()):
+			t = t.Add(time.Minute)
+		default:
+			return t
+		}
+	}
+	return time.Time{}
+}
+
+// matchDay returns true if the day matches the schedule.
+// As with the standard cron, when both days of month and week are restricted, one of them is enough.
+func (s *Schedule) matchDay(t time.Time) bool {
+	dom, dow := has(s.dom, t.Day()), has(s.dow, int(t.Weekday()))
+	if s.anyDom || s.anyDow {
+		return dom && dow
+	}
+	return dom || dow
+}
+
+// parseField returns as bits the values of a field like "*", "1,5", "1-5" or "*/15".
+func parseField(field string, min, max int) (bits uint64, err error) {
+	for _, part := range strings.Split(field, ",") {
+		step, low, high := 1, min, max
+		if pos := strings.Index(part, "/"); pos > -1 {
+			if step, err = strconv.Atoi(part[pos+1:]); err != nil || step < 1 {
+				return 0, errors.New(errMsgExpression)
+			}
+			part = part[:pos]
+		}
+		if part != "*" {
+			rng := strings.SplitN(part, "-", 2)
+			if low, err = strconv.Atoi(rng[0]); err != nil {
+				return 0, errors.New(errMsgExpression)
+			}
+			if high = low; len(rng) == 2 {
+				if high, err = strconv.Atoi(rng[1]); err != nil {
+					return 0, errors.New(errMsgExpression)
+				}
+			} else if step > 1 {
+				// Like "5/15": from 5 to the end.
+				high = max
+			}
+		}
+		if low < min || high > max || low > high {
+			return 0, errors.New(errMsgExpression)
+		}
+		for i := low; i <= high; i += step {
+			bits |= 1 << uint(i)
+		}
+	}
+	return bits, nil
+}
+
+// has returns true if the value is set in the bits.
+func has(bits uint64, value int) bool {
+	return bits&(1<<uint(value)) != 0
+}
@@ -0,0 +1,88 @@
+package cron_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/rvflash/gitup/internal/cron"
+)
+
+var errTests = []struct {
+	expr string // input
+}{
+	{""},
+	{"* * * *"},
+	{"* * * * * *"},
+	{"60 * * * *"},
+	{"* 24 * * *"},
+	{"* * 0 * *"},
+	{"* * * 13 *"},
+	{"* * * * 8"},
+	{"a * * * *"},
+	{"5-1 * * * *"},
+	{"*/0 * * * *"},
+	{"1-a * * * *"},
+}
+
+var nextTests = []struct {
+	expr string    // input
+	from time.Time // input
+	next time.Time // expected result
+}{
+	{"* * * * *", date(2017, 6, 1, 10, 0), date(2017, 6, 1, 10, 1)},
+	{"*/15 * * * *", date(2017, 6, 1, 10, 7), date(2017, 6, 1, 10, 15)},
+	{"0 2 * * *", date(2017, 6, 1, 10, 7), date(2017, 6, 2, 2, 0)},
+	{"30 2-4 * * 1-5", date(2017, 6, 2, 4, 30), date(2017, 6, 5, 2, 30)}, // Friday to Monday
+	{"0 0 1 1 *", date(2017, 6, 1, 10, 0), date(2018, 1, 1, 0, 0)},
+	{"0 0 * * 7", date(2017, 6, 1, 10, 0), date(2017, 6, 4, 0, 0)},      // Sunday
+	{"0 0 13 * 5", date(2017, 6, 1, 10, 0), date(2017, 6, 2, 0, 0)},     // Friday or 13th
+	{"0 0 */1 * 1", date(2017, 6, 1, 10, 0), date(2017, 6, 5, 0, 0)},    // Monday, the step is no restriction
+	{"0 0 13 * */1", date(2017, 6, 1, 10, 0), date(2017, 6, 13, 0, 0)},  // 13th
+	{"5/20 1,3 * * *", date(2017, 6, 1, 1, 50), date(2017, 6, 1, 3, 5)}, // step from value
+	{"0 0 31 2 *", date(2017, 6, 1, 10, 0), time.Time{}},                // never
+	{"0 12 29 2 *", date(2017, 3, 1, 10, 0), date(2020, 2, 29, 12, 0)},  // leap year
+	{"* * * * *", date(2017, 6, 1, 10, 0).Add(30 * time.Second), date(2017, 6, 1, 10, 1)},
+	// Zones whose offset is not a whole number of hours.
+	{"0 2 * * *", dateIn(kolkata, 2017, 6, 1, 10, 7), dateIn(kolkata, 2017, 6, 2, 2, 0)},
+	{"*/15 3 * * *", dateIn(kolkata, 2017, 6, 1, 2, 50), dateIn(kolkata, 2017, 6, 1, 3, 0)},
+	{"45 * * * *", dateIn(kathmandu, 2017, 6, 1, 10, 50), dateIn(kathmandu, 2017, 6, 1, 11, 45)},
+}
+
+// Locations with an offset of half or quarter of hour.
+var (
+	kolkata   = time.FixedZone("IST", 5*3600+30*60)
+	kathmandu = time.FixedZone("NPT", 5*3600+45*60)
+)
+
+// date returns the time in UTC for this date.
+func date(year int, month time.Month, day, hour, min int) time.Time {
+	return time.Date(year, month, day, hour, min, 0, 0, time.UTC)
+}
+
+// dateIn returns the time in the location for this date.
+func dateIn(loc *time.Location, year int, month time.Month, day, hour, min int) time.Time {
+	return time.Date(year, month, day, hour, min, 0, 0, loc)
+}
+
+// TestParse tests Parse method with invalid cron expressions.
+func TestParse(t *testing.T) {
+	for _, et := range errTests {
+		if _, err := cron.Parse(et.expr); err == nil {
+			t.Errorf("Expected error with invalid expression '%v'", et.expr)
+		}
+	}
+}
+
+// TestSchedule_Next tests Next method with various expressions.
+func TestSchedule_Next(t *testing.T) {
+	for _, nt := range nextTests {
+		s, err := cron.Parse(nt.expr)
+		if err != nil {
+			t.Errorf("Expected valid expression '%v', received: %v", nt.expr, err)
+			continue
+		}
+		if next := s.Next(nt.from); !next.Equal(nt.next) {
+			t.Errorf("Expected next time %v for '%v' from %v, received: %v", nt.next, nt.expr, nt.from, next)
+		}
+	}
+}
@@ -0,0 +1,89 @@
+package gitflow
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+)
+
+const errMsgTagSignature = "tag signature not verified"
+
+// CreateBundle writes in the file the tags with their objects not reachable from the basis,
+// all of them without basis. It returns the tags of the bundle with the name of their object.
+func (r *Repo) CreateBundle(file, basis string) (map[string]string, error) {
+	if err := r.gitCheck(); err != nil {
+		return nil, err
+	}
+	file, err := filepath.Abs(file)
+	if err != nil {
+		return nil, err
+	}
+	args := []string{"bundle", "create", file, "--tags"}
+	if basis = strings.TrimSpace(basis); basis != "" {
+		args = append(args, "^"+basis)
+	}
+	if _, err = r.git(args...); err != nil {
+		return nil, err
+	}
+	return r.BundleTags(file)
+}
+
+// BundleTags verifies that the repository has the prerequisites of the bundle,
+// then returns its tags with the name of their object.
+func (r *Repo) BundleTags(file string) (map[string]string, error) {
+	if err := r.gitCheck(); err != nil {
+		return nil, err
+	}
+	file, err := filepath.Abs(file)
+	if err != nil {
+		return nil, err
+	}
+	if _, err = r.git("bundle", "verify", file); err != nil {
+		return nil, err
+	}
+	out, err := r.git("bundle", "list-heads", file)
+	if err != nil {
+		return nil, err
+	}
+	tags := make(map[string]string)
+	for _, line := range strings.Split(string(out), "\n") {
+		fields := strings.Fields(line)
+		if len(fields) == 2 && strings.HasPrefix(fields[1], "refs/"+gitTagFolder) {
+			tags[strings.TrimPrefix(fields[1], "refs/"+gitTagFolder)] = fields[0]
+		}
+	}
+	return tags, nil
+}
+
+// VerifyBundleTags verifies the bundle, then the signature of each of its tags with git verify-tag,
+// against the keys trusted by the local Git configuration. It returns the tags with the name of their object.
+// The objects of the bundle are added to the repository, but none of its tags.
+func (r *Repo) VerifyBundleTags(file string) (map[string]string, error) {
+	tags, err := r.BundleTags(file)
+	if err != nil {
+		return nil, err
+	}
+	file, err = filepath.Abs(file)
+	if err != nil {
+		return nil, err
+	}
+	if _, err = r.git("bundle", "unbundle", file); err != nil {
+		return nil, err
+	}
+	for tag, obj := range tags {
+		if _, err = r.git("verify-tag", obj); err != nil {
+			return nil, fmt.Errorf("%s: %s", errMsgTagSignature, tag)
+		}
+	}
+	return tags, nil
+}
+
+// SetBundle defines the bundle file fetched by LastTag instead of the remote.
+// Empty, the remote is fetched again.
+func (r *Repo) SetBundle(file string) (err error) {
+	if file = strings.TrimSpace(file); file != "" {
+		file, err = filepath.Abs(file)
+	}
+	r.bundle = file
+	return
+}
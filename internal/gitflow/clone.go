@@ -0,0 +1,46 @@
+package gitflow
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+)
+
+const errMsgUndefinedURL = "remote URL is undefined"
+
+// CloneOptions represents the options of a clone: shallow with a depth, partial with a filter.
+type CloneOptions struct {
+	// Depth limits the history to this number of commits, the complete history if zero.
+	Depth int
+	// Filter omits objects until they are needed, like "blob:none".
+	Filter string
+	// Logger receives the logs of the clone, then of the Git commands of the repository.
+	Logger Logger
+}
+
+// Clone clones the remote in the path, without checking out any branch, then returns its repository.
+func Clone(rawURL, path string, o CloneOptions) (*Repo, error) {
+	if rawURL = strings.TrimSpace(rawURL); rawURL == "" {
+		return nil, errors.New(errMsgUndefinedURL)
+	}
+	if path = strings.TrimSpace(path); path == "" {
+		return nil, errors.New(errMsgUndefinedPath)
+	}
+	args := []string{"clone", "--quiet", "--no-checkout"}
+	if o.Depth > 0 {
+		args = append(args, "--depth", strconv.Itoa(o.Depth), "--no-single-branch")
+	}
+	if o.Filter = strings.TrimSpace(o.Filter); o.Filter != "" {
+		args = append(args, "--filter="+o.Filter)
+	}
+	args = append(args, "--", rawURL, path)
+	if _, err := run(o.Logger, path, args, args); err != nil {
+		return nil, err
+	}
+	r, err := NewRepo(path)
+	if err != nil {
+		return nil, err
+	}
+	r.SetLogger(o.Logger)
+	return r, nil
+}
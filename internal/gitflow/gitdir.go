@@ -0,0 +1,529 @@
+package gitflow
+
+import (
+	"bufio"
+	"bytes"
+	"compress/zlib"
+	"crypto/sha1"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+const (
+	errMsgNotGitDir = "not a git directory"
+	errMsgRef       = "unable to resolve the reference"
+	errMsgObject    = "object not readable without git"
+	symRefPrefix    = "ref: "
+	gitDirPrefix    = "gitdir: "
+	maxSymRefDepth  = 5
+)
+
+// tagRef is a tag with the object it references and the commit it peels to, empty if unknown.
+type tagRef struct {
+	name, object, peeled string
+}
+
+// gitDir reads the references of a repository straight from its .git directory, without process.
+// The dir is the one of the worktree, with its HEAD, the common one has the shared references and objects.
+// Anything it can not resolve is left to git itself.
+type gitDir struct {
+	dir, common string
+}
+
+// openGitDir returns the git directory of the working tree: its .git directory,
+// or with a linked worktree or a submodule, the one named by its .git file.
+func openGitDir(path string) (*gitDir, error) {
+	dir := filepath.Join(path, ".git")
+	fi, err := os.Stat(dir)
+	if err != nil {
+		return nil, err
+	}
+	if !fi.IsDir() {
+		var buf []byte
+		if buf, err = ioutil.ReadFile(dir); err != nil {
+			return nil, err
+		}
+		line := strings.TrimSpace(string(buf))
+		if !strings.HasPrefix(line, gitDirPrefix) {
+			return nil, errors.New(errMsgNotGitDir)
+		}
+		dir = resolvePath(path, strings.TrimPrefix(line, gitDirPrefix))
+	}
+	if _, err = os.Stat(filepath.Join(dir, "HEAD")); err != nil {
+		return nil, errors.New(errMsgNotGitDir)
+	}
+	d := &gitDir{dir: dir, common: dir}
+	if buf, err := ioutil.ReadFile(filepath.Join(dir, "commondir")); err == nil {
+		d.common = resolvePath(dir, strings.TrimSpace(string(buf)))
+	}
+	return d, nil
+}
+
+// head returns the commit checked out.
+func (d *gitDir) head() (string, error) {
+	return d.resolve("HEAD")
+}
+
+// resolve returns the object of the reference, following the symbolic references.
+func (d *gitDir) resolve(name string) (string, error) {
+	for i := 0; i < maxSymRefDepth; i++ {
+		value, err := d.ref(name)
+		if err != nil {
+			return "", err
+		}
+		if !strings.HasPrefix(value, symRefPrefix) {
+			return value, nil
+		}
+		name = strings.TrimPrefix(value, symRefPrefix)
+	}
+	return "", errors.New(errMsgRef)
+}
+
+// ref returns the value of the reference: loose in the git directory, in the common one, then packed.
+func (d *gitDir) ref(name string) (string, error) {
+	for _, dir := range []string{d.dir, d.common} {
+		if buf, err := ioutil.ReadFile(filepath.Join(dir, filepath.FromSlash(name))); err == nil {
+			return strings.TrimSpace(string(buf)), nil
+		}
+	}
+	packed, err := d.packedRefs()
+	if err != nil {
+		return "", err
+	}
+	for _, t := range packed {
+		if t.name == name {
+			return t.object, nil
+		}
+	}
+	return "", errors.New(errMsgRef)
+}
+
+// remoteURL returns the URL of the named remote, read in the config file of the common directory.
+func (d *gitDir) remoteURL(name string) (string, error) {
+	buf, err := ioutil.ReadFile(filepath.Join(d.common, "config"))
+	if err != nil {
+		return "", err
+	}
+	var in bool
+	sc := bufio.NewScanner(bytes.NewReader(buf))
+	for sc.Scan() {
+		line := strings.TrimSpace(sc.Text())
+		switch {
+		case strings.HasPrefix(line, "["):
+			in = line == `[remote "`+name+`"]`
+		case in:
+			kv := strings.SplitN(line, "=", 2)
+			if len(kv) == 2 && strings.EqualFold(strings.TrimSpace(kv[0]), "url") {
+				return strings.TrimSpace(kv[1]), nil
+			}
+		}
+	}
+	return "", errors.New(errMsgRef)
+}
+
+// tags returns the tags ordered by name, with the commit they peel to if it is known.
+func (d *gitDir) tags() ([]tagRef, error) {
+	refs := make(map[string]tagRef)
+	packed, err := d.packedRefs()
+	if err != nil {
+		return nil, err
+	}
+	for _, t := range packed {
+		if strings.HasPrefix(t.name, "refs/"+gitTagFolder) {
+			if t.peeled == "" {
+				// Without the trait fully-peeled, the packed-refs may miss the peeled lines.
+				t.peeled = d.peel(t.object)
+			}
+			refs[t.name] = t
+		}
+	}
+	// The loose references take precedence over the packed ones.
+	root := filepath.Join(d.common, "refs", "tags")
+	err = filepath.Walk(root, func(path string, fi os.FileInfo, err error) error {
+		if err != nil || fi.IsDir() {
+			return err
+		}
+		buf, err := ioutil.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(d.common, path)
+		if err != nil {
+			return err
+		}
+		t := tagRef{name: filepath.ToSlash(rel), object: strings.TrimSpace(string(buf))}
+		t.peeled = d.peel(t.object)
+		refs[t.name] = t
+		return nil
+	})
+	if err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+	res := make([]tagRef, 0, len(refs))
+	for _, t := range refs {
+		t.name = strings.TrimPrefix(t.name, "refs/"+gitTagFolder)
+		res = append(res, t)
+	}
+	sort.Slice(res, func(i, j int) bool { return res[i].name < res[j].name })
+	return res, nil
+}
+
+// tagsAt returns the tags peeling to the commit, by descending precedence for the semantic versions.
+// It fails if the commit of any tag is unknown, since it could be one of them.
+func (d *gitDir) tagsAt(commit string) ([]string, error) {
+	list, err := d.tags()
+	if err != nil {
+		return nil, err
+	}
+	var tags []string
+	for _, t := range list {
+		switch t.peeled {
+		case "":
+			return nil, errors.New(errMsgObject)
+		case commit:
+			tags = append(tags, t.name)
+		}
+	}
+	return byDescendingPrecedence(tags), nil
+}
+
+// packedRefs returns the references of the packed-refs file, with their peeled commit.
+// With the trait fully-peeled, a reference without peeled line is not an annotated tag.
+func (d *gitDir) packedRefs() ([]tagRef, error) {
+	buf, err := ioutil.ReadFile(filepath.Join(d.common, "packed-refs"))
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var (
+		refs  []tagRef
+		peels bool
+	)
+	sc := bufio.NewScanner(bytes.NewReader(buf))
+	for sc.Scan() {
+		line := sc.Text()
+		switch {
+		case strings.HasPrefix(line, "#"):
+			peels = strings.Contains(line, " fully-peeled")
+		case strings.HasPrefix(line, "^"):
+			if n := len(refs); n > 0 {
+				refs[n-1].peeled = line[1:]
+			}
+		default:
+			fields := strings.Fields(line)
+			if len(fields) != 2 {
+				continue
+			}
+			t := tagRef{name: fields[1], object: fields[0]}
+			if peels {
+				t.peeled = t.object
+			}
+			refs = append(refs, t)
+		}
+	}
+	return refs, sc.Err()
+}
+
+// peel returns the commit referenced by the object, if it is a commit or an annotated tag
+// readable without git. Otherwise, it returns an empty string.
+func (d *gitDir) peel(object string) string {
+	for i := 0; i < maxSymRefDepth; i++ {
+		kind, body, err := d.object(object)
+		if err != nil {
+			return ""
+		}
+		switch kind {
+		case "commit":
+			return object
+		case "tag":
+			// The first line of an annotated tag is the object it references.
+			line := strings.SplitN(string(body), "\n", 2)[0]
+			if !strings.HasPrefix(line, "object ") {
+				return ""
+			}
+			object = strings.TrimPrefix(line, "object ")
+		default:
+			return ""
+		}
+	}
+	return ""
+}
+
+// object returns the type and the content of an object, loose or in a pack.
+func (d *gitDir) object(name string) (kind string, body []byte, err error) {
+	if len(name) < 3 {
+		return "", nil, errors.New(errMsgObject)
+	}
+	f, err := os.Open(filepath.Join(d.common, "objects", name[:2], name[2:]))
+	if os.IsNotExist(err) {
+		return d.packedObject(name)
+	}
+	if err != nil {
+		return
+	}
+	defer f.Close()
+	zr, err := zlib.NewReader(f)
+	if err != nil {
+		return
+	}
+	defer zr.Close()
+	buf, err := ioutil.ReadAll(zr)
+	if err != nil {
+		return
+	}
+	// Header: type, space, size, then a null byte.
+	i := bytes.IndexByte(buf, 0)
+	if i < 0 {
+		return "", nil, errors.New(errMsgObject)
+	}
+	kind = strings.SplitN(string(buf[:i]), " ", 2)[0]
+	return kind, buf[i+1:], nil
+}
+
+// Pack settings: the header of an index in version 2, then its fan-out table,
+// and the maximum length of a chain of deltas.
+const (
+	packIdxHeader = "\xfftOc\x00\x00\x00\x02"
+	packFanout    = len(packIdxHeader) + 256*4
+	maxDeltaDepth = 50
+)
+
+// Types of the objects of a pack stored as a delta of another object.
+const (
+	packOfsDelta = 6
+	packRefDelta = 7
+)
+
+// packObjectTypes lists the types of the objects of a pack, except the deltas.
+var packObjectTypes = map[byte]string{1: "commit", 2: "tree", 3: "blob", 4: "tag"}
+
+// packedObject returns the type and the content of an object of the packs.
+func (d *gitDir) packedObject(name string) (kind string, body []byte, err error) {
+	return d.packedObjectAt(name, 0)
+}
+
+// packedObjectAt returns the object of the packs, at this depth of a chain of deltas.
+func (d *gitDir) packedObjectAt(name string, depth int) (kind string, body []byte, err error) {
+	id, err := hex.DecodeString(name)
+	if err != nil || len(id) != sha1.Size || depth > maxDeltaDepth {
+		return "", nil, errors.New(errMsgObject)
+	}
+	idx, err := filepath.Glob(filepath.Join(d.common, "objects", "pack", "pack-*.idx"))
+	if err != nil {
+		return
+	}
+	for _, file := range idx {
+		var offset int64
+		if offset, err = packOffset(file, id); err != nil {
+			return
+		}
+		if offset >= 0 {
+			return d.packEntry(strings.TrimSuffix(file, ".idx")+".pack", offset, depth)
+		}
+	}
+	return "", nil, errors.New(errMsgObject)
+}
+
+// packOffset returns the offset of the object in the pack of the index, -1 if it is not there.
+func packOffset(file string, id []byte) (int64, error) {
+	f, err := os.Open(file)
+	if err != nil {
+		return -1, err
+	}
+	defer f.Close()
+
+	header := make([]byte, packFanout)
+	if _, err = f.ReadAt(header, 0); err != nil {
+		return -1, err
+	}
+	if string(header[:len(packIdxHeader)]) != packIdxHeader {
+		return -1, errors.New(errMsgObject)
+	}
+	// The fan-out table counts the objects whose first byte is less than or equal to its index.
+	fanout := func(i int) int64 {
+		return int64(binary.BigEndian.Uint32(header[len(packIdxHeader)+i*4:]))
+	}
+	var lo, hi, count = int64(0), fanout(int(id[0])), fanout(255)
+	if id[0] > 0 {
+		lo = fanout(int(id[0]) - 1)
+	}
+	buf := make([]byte, sha1.Size)
+	for lo < hi {
+		i := (lo + hi) / 2
+		if _, err = f.ReadAt(buf, int64(packFanout)+i*sha1.Size); err != nil {
+			return -1, err
+		}
+		switch c := bytes.Compare(buf, id); {
+		case c < 0:
+			lo = i + 1
+		case c > 0:
+			hi = i
+		default:
+			// The names are followed by their CRC, then their offset, the large ones in another table.
+			offsets := int64(packFanout) + count*(sha1.Size+4)
+			if _, err = f.ReadAt(buf[:4], offsets+i*4); err != nil {
+				return -1, err
+			}
+			offset := binary.BigEndian.Uint32(buf[:4])
+			if offset&0x80000000 == 0 {
+				return int64(offset), nil
+			}
+			if _, err = f.ReadAt(buf[:8], offsets+count*4+int64(offset&0x7fffffff)*8); err != nil {
+				return -1, err
+			}
+			return int64(binary.BigEndian.Uint64(buf[:8])), nil
+		}
+	}
+	return -1, nil
+}
+
+// packEntry returns the type and the content of the object at the offset of the pack,
+// applying its delta on its base object if it is stored as a delta.
+func (d *gitDir) packEntry(file string, offset int64, depth int) (kind string, body []byte, err error) {
+	if depth > maxDeltaDepth {
+		return "", nil, errors.New(errMsgObject)
+	}
+	f, err := os.Open(file)
+	if err != nil {
+		return
+	}
+	defer f.Close()
+
+	r := bufio.NewReader(io.NewSectionReader(f, offset, 1<<62))
+	// Header: the type and the size of the object, by groups of 7 bits while the high bit is set.
+	b, err := r.ReadByte()
+	if err != nil {
+		return
+	}
+	typ, size := b>>4&7, int64(b&15)
+	for shift := uint(4); b&0x80 != 0; shift += 7 {
+		if b, err = r.ReadByte(); err != nil {
+			return
+		}
+		size |= int64(b&0x7f) << shift
+	}
+	var base []byte
+	switch typ {
+	case packOfsDelta:
+		// The base is before in the pack, at a distance encoded by groups of 7 bits.
+		if b, err = r.ReadByte(); err != nil {
+			return
+		}
+		distance := int64(b & 0x7f)
+		for b&0x80 != 0 {
+			if b, err = r.ReadByte(); err != nil {
+				return
+			}
+			distance = (distance+1)<<7 | int64(b&0x7f)
+		}
+		if kind, base, err = d.packEntry(file, offset-distance, depth+1); err != nil {
+			return
+		}
+	case packRefDelta:
+		// The base is named.
+		id := make([]byte, sha1.Size)
+		if _, err = io.ReadFull(r, id); err != nil {
+			return
+		}
+		if kind, base, err = d.packedObjectAt(hex.EncodeToString(id), depth+1); err != nil {
+			return
+		}
+	default:
+		var ok bool
+		if kind, ok = packObjectTypes[typ]; !ok {
+			return "", nil, errors.New(errMsgObject)
+		}
+	}
+	zr, err := zlib.NewReader(r)
+	if err != nil {
+		return
+	}
+	defer zr.Close()
+	if body, err = ioutil.ReadAll(io.LimitReader(zr, size)); err != nil {
+		return
+	}
+	if base != nil {
+		body, err = applyDelta(base, body)
+	}
+	return kind, body, err
+}
+
+// applyDelta returns the object built with the delta from its base: the sizes of both of them,
+// then instructions copying a part of the base or inserting the following bytes.
+func applyDelta(base, delta []byte) ([]byte, error) {
+	size := func() (n int) {
+		for shift := uint(0); len(delta) > 0; shift += 7 {
+			b := delta[0]
+			delta = delta[1:]
+			n |= int(b&0x7f) << shift
+			if b&0x80 == 0 {
+				break
+			}
+		}
+		return
+	}
+	if size() != len(base) {
+		return nil, errors.New(errMsgObject)
+	}
+	res := make([]byte, 0, size())
+	for len(delta) > 0 {
+		op := delta[0]
+		delta = delta[1:]
+		switch {
+		case op&0x80 != 0:
+			// Copy: the bits of the operation tell the bytes of the offset, then of the size.
+			var offset, n int
+			for i := uint(0); i < 7; i++ {
+				if op&(1<<i) == 0 {
+					continue
+				}
+				if len(delta) == 0 {
+					return nil, errors.New(errMsgObject)
+				}
+				if i < 4 {
+					offset |= int(delta[0]) << (8 * i)
+				} else {
+					n |= int(delta[0]) << (8 * (i - 4))
+				}
+				delta = delta[1:]
+			}
+			if n == 0 {
+				n = 0x10000
+			}
+			if offset+n > len(base) {
+				return nil, errors.New(errMsgObject)
+			}
+			res = append(res, base[offset:offset+n]...)
+		case op != 0:
+			// Insert.
+			if int(op) > len(delta) {
+				return nil, errors.New(errMsgObject)
+			}
+			res = append(res, delta[:op]...)
+			delta = delta[op:]
+		default:
+			return nil, errors.New(errMsgObject)
+		}
+	}
+	if len(res) != cap(res) {
+		return nil, errors.New(errMsgObject)
+	}
+	return res, nil
+}
+
+// resolvePath returns the path, relative to the directory if it is not absolute.
+func resolvePath(dir, path string) string {
+	if filepath.IsAbs(path) {
+		return filepath.Clean(path)
+	}
+	return filepath.Join(dir, path)
+}
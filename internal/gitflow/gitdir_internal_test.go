@@ -0,0 +1,275 @@
+package gitflow
+
+import (
+	"bytes"
+	"compress/zlib"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+)
+
+const (
+	headTest      = "1111111111111111111111111111111111111111"
+	oldCommitTest = "2222222222222222222222222222222222222222"
+	tagObjectTest = "3333333333333333333333333333333333333333"
+	packedTagTest = "4444444444444444444444444444444444444444"
+)
+
+// writeTestFile writes the content in the file, created with its parent directories.
+func writeTestFile(t *testing.T, name string, buf []byte) {
+	if err := os.MkdirAll(filepath.Dir(name), 0755); err != nil {
+		t.Fatalf("Unable to create directory, received: %v", err)
+	}
+	if err := ioutil.WriteFile(name, buf, 0644); err != nil {
+		t.Fatalf("Unable to write %v, received: %v", name, err)
+	}
+}
+
+// writeTestObject writes a loose object.
+func writeTestObject(t *testing.T, dir, name, kind, body string) {
+	var buf bytes.Buffer
+	zw := zlib.NewWriter(&buf)
+	fmt.Fprintf(zw, "%s %d\x00%s", kind, len(body), body)
+	zw.Close()
+	writeTestFile(t, filepath.Join(dir, "objects", name[:2], name[2:]), buf.Bytes())
+}
+
+// writeTestPack writes a pack of the objects, given by name, type and content, with its index in version 2.
+func writeTestPack(t *testing.T, dir string, objects ...[3]string) {
+	types := map[string]byte{"commit": 1, "tree": 2, "blob": 3, "tag": 4}
+	pack := bytes.NewBufferString("PACK")
+	binary.Write(pack, binary.BigEndian, [2]uint32{2, uint32(len(objects))})
+	offsets := make(map[string]uint32)
+	for _, o := range objects {
+		offsets[o[0]] = uint32(pack.Len())
+		// Header: the type and the size, by groups of 7 bits while the high bit is set.
+		size := len(o[2])
+		b := types[o[1]]<<4 | byte(size&15)
+		for size >>= 4; size > 0; size >>= 7 {
+			pack.WriteByte(b | 0x80)
+			b = byte(size & 0x7f)
+		}
+		pack.WriteByte(b)
+		zw := zlib.NewWriter(pack)
+		zw.Write([]byte(o[2]))
+		zw.Close()
+	}
+	names := make([]string, 0, len(objects))
+	for name := range offsets {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	idx := bytes.NewBufferString("\xfftOc\x00\x00\x00\x02")
+	var fanout [256]uint32
+	for _, name := range names {
+		id, _ := hex.DecodeString(name)
+		for i := int(id[0]); i < 256; i++ {
+			fanout[i]++
+		}
+	}
+	binary.Write(idx, binary.BigEndian, fanout)
+	for _, name := range names {
+		id, _ := hex.DecodeString(name)
+		idx.Write(id)
+	}
+	idx.Write(make([]byte, 4*len(names)))
+	for _, name := range names {
+		binary.Write(idx, binary.BigEndian, offsets[name])
+	}
+	writeTestFile(t, filepath.Join(dir, "objects", "pack", "pack-test.pack"), pack.Bytes())
+	writeTestFile(t, filepath.Join(dir, "objects", "pack", "pack-test.idx"), idx.Bytes())
+}
+
+// newTestGitDir returns a working tree whose HEAD is on a branch with the tags v1.2.0, annotated and loose,
+// and v1.2.0-rc.1, lightweight and packed, and another one with v1.0.0, annotated and packed.
+// The linked worktree, named "linked", is detached on the older commit. Both have the remote origin.
+func newTestGitDir(t *testing.T) (dir string) {
+	var err error
+	if dir, err = ioutil.TempDir(os.TempDir(), "gitflow"); err != nil {
+		t.Fatalf("Unable to create directory, received: %v", err)
+	}
+	git := filepath.Join(dir, "app", ".git")
+	writeTestFile(t, filepath.Join(git, "HEAD"), []byte("ref: refs/heads/stable\n"))
+	writeTestFile(t, filepath.Join(git, "config"), []byte("[core]\n\tbare = false\n[remote \"upstream\"]\n\turl = "+
+		remoteURLTest+".old\n[remote \"origin\"]\n\turl = "+remoteURLTest+"\n\tfetch = +refs/heads/*:refs/remotes/origin/*\n"))
+	writeTestFile(t, filepath.Join(git, "refs", "heads", "stable"), []byte(headTest+"\n"))
+	writeTestFile(t, filepath.Join(git, "refs", "tags", "v1.2.0"), []byte(tagObjectTest+"\n"))
+	writeTestObject(t, git, tagObjectTest, "tag", "object "+headTest+"\ntype commit\ntag v1.2.0\n\nv1.2.0\n")
+	writeTestObject(t, git, headTest, "commit", "tree 0\n\nv1.2.0\n")
+	writeTestFile(t, filepath.Join(git, "packed-refs"), []byte("# pack-refs with: peeled fully-peeled sorted \n"+
+		headTest+" refs/heads/master\n"+
+		packedTagTest+" refs/tags/v1.0.0\n^"+oldCommitTest+"\n"+
+		headTest+" refs/tags/v1.2.0-rc.1\n"))
+	// Linked worktree.
+	linked := filepath.Join(git, "worktrees", "linked")
+	writeTestFile(t, filepath.Join(linked, "HEAD"), []byte(oldCommitTest+"\n"))
+	writeTestFile(t, filepath.Join(linked, "commondir"), []byte("../..\n"))
+	writeTestFile(t, filepath.Join(dir, "linked", ".git"), []byte("gitdir: "+linked+"\n"))
+	return
+}
+
+// TestGitDir tests the reading of the references without git.
+func TestGitDir(t *testing.T) {
+	dir := newTestGitDir(t)
+	defer os.RemoveAll(dir)
+
+	if _, err := openGitDir(dir); err == nil {
+		t.Error("Expected error without git directory")
+	}
+	d, err := openGitDir(filepath.Join(dir, "app"))
+	if err != nil {
+		t.Fatalf("Expected no error, received: %v", err)
+	}
+	if head, err := d.head(); err != nil || head != headTest {
+		t.Errorf("Expected HEAD on %v, received: %v, %v", headTest, head, err)
+	}
+	if ref, err := d.resolve("refs/heads/master"); err != nil || ref != headTest {
+		t.Errorf("Expected the packed branch on %v, received: %v, %v", headTest, ref, err)
+	}
+	if _, err = d.resolve("refs/heads/unknown"); err == nil {
+		t.Error("Expected error with an unknown reference")
+	}
+	tags, err := d.tags()
+	if err != nil {
+		t.Fatalf("Expected no error, received: %v", err)
+	}
+	expected := []tagRef{
+		{"v1.0.0", packedTagTest, oldCommitTest},
+		{"v1.2.0", tagObjectTest, headTest},
+		{"v1.2.0-rc.1", headTest, headTest},
+	}
+	if fmt.Sprint(tags) != fmt.Sprint(expected) {
+		t.Errorf("Expected the tags %v, received: %v", expected, tags)
+	}
+	if at, err := d.tagsAt(headTest); err != nil || fmt.Sprint(at) != "[v1.2.0 v1.2.0-rc.1]" {
+		t.Errorf("Expected the tags of HEAD by precedence, received: %v, %v", at, err)
+	}
+	// Linked worktree.
+	if d, err = openGitDir(filepath.Join(dir, "linked")); err != nil {
+		t.Fatalf("Expected no error on the linked worktree, received: %v", err)
+	}
+	if head, err := d.head(); err != nil || head != oldCommitTest {
+		t.Errorf("Expected HEAD on %v, received: %v, %v", oldCommitTest, head, err)
+	}
+	if at, err := d.tagsAt(oldCommitTest); err != nil || fmt.Sprint(at) != "[v1.0.0]" {
+		t.Errorf("Expected the tag v1.0.0, received: %v, %v", at, err)
+	}
+	if url, err := d.remoteURL("origin"); err != nil || url != remoteURLTest {
+		t.Errorf("Expected the remote %v, received: %v, %v", remoteURLTest, url, err)
+	}
+	if _, err = d.remoteURL("unknown"); err == nil {
+		t.Error("Expected error with an unknown remote")
+	}
+	// An object missing in the packs is unknown.
+	git := filepath.Join(dir, "app", ".git")
+	os.RemoveAll(filepath.Join(git, "objects", tagObjectTest[:2]))
+	if _, err = d.tagsAt(headTest); err == nil {
+		t.Error("Expected error with a tag whose commit is unknown")
+	}
+	// The tags are peeled with the objects of the packs, even without peeled line in the packed-refs.
+	writeTestPack(t, git,
+		[3]string{tagObjectTest, "tag", "object " + headTest + "\ntype commit\ntag v1.2.0\n\n" + strings.Repeat("v1.2.0\n", 40)},
+		[3]string{packedTagTest, "tag", "object " + oldCommitTest + "\ntype commit\ntag v1.0.0\n\nv1.0.0\n"},
+		[3]string{oldCommitTest, "commit", "tree 0\n\nv1.0.0\n"},
+	)
+	writeTestFile(t, filepath.Join(git, "packed-refs"), []byte(packedTagTest+" refs/tags/v1.0.0\n"))
+	if at, err := d.tagsAt(headTest); err != nil || fmt.Sprint(at) != "[v1.2.0]" {
+		t.Errorf("Expected the packed tag of HEAD, received: %v, %v", at, err)
+	}
+	if at, err := d.tagsAt(oldCommitTest); err != nil || fmt.Sprint(at) != "[v1.0.0]" {
+		t.Errorf("Expected the tag v1.0.0 peeled with the pack, received: %v, %v", at, err)
+	}
+}
+
+// TestRepo_LocalState_Mixed tests that the highest release of HEAD is its nearest tag, despite other tags.
+func TestRepo_LocalState_Mixed(t *testing.T) {
+	dir := newTestGitDir(t)
+	defer os.RemoveAll(dir)
+
+	execCommand = fakeExecCommand
+
+	// Restore exec command behavior at the end of the test.
+	defer func() { execCommand = exec.Command }()
+
+	git := filepath.Join(dir, "app", ".git")
+	for _, tag := range []string{"latest", "stable"} {
+		writeTestFile(t, filepath.Join(git, "refs", "tags", tag), []byte(headTest+"\n"))
+	}
+	r, err := NewRepo(filepath.Join(dir, "app"))
+	if err != nil {
+		t.Fatalf("Expected no error, received: %v", err)
+	}
+	s, err := r.LocalState()
+	if err != nil || s.Nearest != "v1.2.0" || !s.OnRelease || fmt.Sprint(s.Tags) != "[v1.2.0 v1.2.0-rc.1 latest stable]" {
+		t.Errorf("Expected HEAD on the release v1.2.0, received: %+v, %v", s, err)
+	}
+	if tag, err := r.LocalTag(); err != nil || tag != "v1.2.0" {
+		t.Errorf("Expected v1.2.0, received: %v, %v", tag, err)
+	}
+}
+
+var deltaTests = []struct {
+	base, delta string // input
+	res         string // expected result
+	ok          bool   // expected result
+}{
+	{"tag v1.0.0\n", "\x0b\x0b\x90\x09\x01\x31\x91\x0a\x01", "tag v1.0.1\n", true},
+	{"tag v1.0.0\n", "\x0b\x06\x02ab\x91\x05\x04", "ab1.0.", true},
+	{"tag v1.0.0\n", "\x0a\x0b\x90\x0a\x01\x31", "", false},
+	{"tag v1.0.0\n", "\x0b\x0b\x90\x0c", "", false},
+	{"tag v1.0.0\n", "\x0b\x02\x05ab", "", false},
+	{"tag v1.0.0\n", "\x0b\x02\x00", "", false},
+	{"tag v1.0.0\n", "\x0b\x03\x02ab", "", false},
+}
+
+// TestApplyDelta tests the building of an object of a pack with its delta.
+func TestApplyDelta(t *testing.T) {
+	for i, tt := range deltaTests {
+		res, err := applyDelta([]byte(tt.base), []byte(tt.delta))
+		if tt.ok != (err == nil) {
+			t.Errorf("%d. Expected success: %v, received: %v", i, tt.ok, err)
+		} else if string(res) != tt.res {
+			t.Errorf("%d. Expected %q, received: %q", i, tt.res, res)
+		}
+	}
+}
+
+// TestRepo_LocalTag_GitDir tests that the tag of HEAD is read without git.
+func TestRepo_LocalTag_GitDir(t *testing.T) {
+	dir := newTestGitDir(t)
+	defer os.RemoveAll(dir)
+
+	execCommand = fakeExecCommand
+
+	// Restore exec command behavior at the end of the test.
+	defer func() { execCommand = exec.Command }()
+
+	r, err := NewRepo(filepath.Join(dir, "app"))
+	if err != nil {
+		t.Fatalf("Expected no error, received: %v", err)
+	}
+	if !r.valid || r.dir == nil {
+		t.Fatal("Expected a valid repository read without git")
+	}
+	if tag, err := r.LocalTag(); err != nil || tag != "v1.2.0" {
+		t.Errorf("Expected v1.2.0, received: %v, %v", tag, err)
+	}
+	if s, err := r.LocalState(); err != nil || fmt.Sprint(s.Tags) != "[v1.2.0 v1.2.0-rc.1]" || !s.OnRelease || s.Ahead() {
+		t.Errorf("Expected HEAD on the release v1.2.0 and v1.2.0-rc.1, received: %+v, %v", s, err)
+	}
+	if tags, err := r.Tags(); err != nil || fmt.Sprint(tags) != "[v1.0.0 v1.2.0 v1.2.0-rc.1]" {
+		t.Errorf("Expected the tags without git, received: %v, %v", tags, err)
+	}
+	// Without the object of the annotated tag, git describe is used.
+	os.RemoveAll(filepath.Join(dir, "app", ".git", "objects", tagObjectTest[:2]))
+	if tag, err := r.LocalTag(); err != nil || tag != tagTest {
+		t.Errorf("Expected git describe to be used, received: %v, %v", tag, err)
+	}
+}
@@ -0,0 +1,153 @@
+package gitflow
+
+import (
+	"errors"
+	"strings"
+
+	"github.com/rvflash/gitup/internal/semver"
+)
+
+// LocalState is the position of HEAD relative to the tags.
+type LocalState struct {
+	// Head is the commit checked out.
+	Head string
+	// Tags are the tags of HEAD, the semantic versions by descending precedence first.
+	Tags []string
+	// Nearest is the tag of HEAD with the highest precedence, or else the one of its nearest tagged ancestor.
+	// It is empty if no tag is reachable.
+	Nearest string
+	// Distance is the number of commits between the nearest tag and HEAD.
+	Distance int
+	// OnRelease is true if HEAD is tagged with a semantic version.
+	OnRelease bool
+}
+
+// Ahead returns true if HEAD has commits after its nearest tag.
+func (s LocalState) Ahead() bool {
+	return s.Distance > 0
+}
+
+// LocalState returns the position of HEAD relative to the tags.
+// The tags are read in the git directory whenever possible, git describe only looks for the nearest ancestor.
+func (r *Repo) LocalState() (s LocalState, err error) {
+	if err = r.gitCheck(); err != nil {
+		return
+	}
+	if s.Head, err = r.head(); err != nil {
+		return
+	}
+	var list []Tag
+	if s.Tags, err = r.tagsAt(s.Head, &list); err != nil {
+		return
+	}
+	if len(s.Tags) > 0 {
+		s.Nearest, s.OnRelease = s.Tags[0], len(semver.Sort(s.Tags)) > 0
+		return
+	}
+	// Nearest tagged ancestor, with the tag of the highest precedence of its commit.
+	var tag string
+	if tag, s.Distance, err = r.gitDescribe(); err != nil || tag == "" {
+		return
+	}
+	var commit string
+	if commit, err = r.tagCommit(tag, &list); err != nil {
+		return
+	}
+	var tags []string
+	if tags, err = r.tagsAt(commit, &list); err != nil {
+		return
+	}
+	s.Nearest = tag
+	if len(tags) > 0 {
+		s.Nearest = tags[0]
+	}
+	return
+}
+
+// tagsAt returns the tags of the commit, by descending precedence.
+// Unless the git directory knows them, the list of tags is loaded once in list.
+func (r *Repo) tagsAt(commit string, list *[]Tag) ([]string, error) {
+	if r.dir != nil && *list == nil {
+		if tags, err := r.dir.tagsAt(commit); err == nil {
+			return tags, nil
+		}
+	}
+	if *list == nil {
+		tags, err := r.ListTags()
+		if err != nil {
+			return nil, err
+		}
+		*list = tags
+	}
+	return tagsOf(*list, commit), nil
+}
+
+// tagCommit returns the commit of the tag.
+// Unless the git directory knows it, the list of tags is loaded once in list.
+func (r *Repo) tagCommit(tag string, list *[]Tag) (string, error) {
+	if r.dir != nil && *list == nil {
+		if refs, err := r.dir.tags(); err == nil {
+			for _, t := range refs {
+				if t.name == tag && t.peeled != "" {
+					return t.peeled, nil
+				}
+			}
+		}
+	}
+	if *list == nil {
+		tags, err := r.ListTags()
+		if err != nil {
+			return "", err
+		}
+		*list = tags
+	}
+	for _, t := range *list {
+		if t.Name == tag {
+			return t.Commit, nil
+		}
+	}
+	return "", errors.New(errMsgUndefinedTag)
+}
+
+// head returns the commit checked out.
+func (r *Repo) head() (string, error) {
+	if r.dir != nil {
+		if commit, err := r.dir.head(); err == nil {
+			return commit, nil
+		}
+	}
+	out, err := r.git("rev-parse", "HEAD")
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(string(out)), nil
+}
+
+// tagsOf returns the tags of the commit, by descending precedence.
+func tagsOf(list []Tag, commit string) []string {
+	var tags []string
+	for _, t := range list {
+		if t.Commit == commit {
+			tags = append(tags, t.Name)
+		}
+	}
+	return byDescendingPrecedence(tags)
+}
+
+// byDescendingPrecedence returns the semantic versions by descending precedence,
+// followed by the other tags in their order, so that the first one is the highest release if there is one.
+func byDescendingPrecedence(tags []string) []string {
+	if len(tags) == 0 {
+		return nil
+	}
+	sorted := semver.Sort(tags)
+	for i, j := 0, len(sorted)-1; i < j; i, j = i+1, j-1 {
+		sorted[i], sorted[j] = sorted[j], sorted[i]
+	}
+	for _, tag := range tags {
+		if _, err := semver.Parse(tag); err != nil {
+			sorted = append(sorted, tag)
+		}
+	}
+	return sorted
+}
@@ -0,0 +1,101 @@
+package gitflow
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/rvflash/gitup/internal/semver"
+)
+
+const errMsgNoTag = "no tag available"
+
+// tagFormat is the format of a tag listed by git for-each-ref: its fields separated by a null byte.
+const tagFormat = "--format=%(refname:strip=2)%00%(objectname)%00%(*objectname)%00%(creatordate:unix)%00" +
+	"%(if)%(contents:signature)%(then)signed%(end)%00%(contents:subject)"
+
+// Tag is a tag with its metadata.
+type Tag struct {
+	Name string
+	// Object is the object referenced by the tag, the tag object itself for an annotated tag.
+	Object string
+	// Commit is the commit targeted by the tag.
+	Commit string
+	// Date is the tagger date of an annotated tag, the commit date otherwise.
+	Date time.Time
+	// Signed is true if the annotated tag carries a signature.
+	Signed bool
+	// Subject is the first line of the message of the annotated tag, of the commit otherwise.
+	Subject string
+}
+
+// ListTags returns the tags known by the local repository with their metadata, ordered by name.
+// It does not fetch the remote, LastTag must be used before to get the new ones.
+func (r *Repo) ListTags() ([]Tag, error) {
+	if err := r.gitCheck(); err != nil {
+		return nil, err
+	}
+	out, err := r.git("for-each-ref", tagFormat, "refs/"+gitTagFolder)
+	if err != nil {
+		return nil, err
+	}
+	tags, err := parseTags(string(out))
+	if err != nil {
+		return nil, err
+	}
+	dates := make(map[string]time.Time, len(tags))
+	for _, t := range tags {
+		dates[t.Name] = t.Date
+	}
+	r.mu.Lock()
+	r.dates = dates
+	r.mu.Unlock()
+	return tags, nil
+}
+
+// parseTags returns the tags listed with the tag format.
+func parseTags(out string) (tags []Tag, err error) {
+	for _, line := range strings.Split(out, "\n") {
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+		f := strings.SplitN(line, "\x00", 6)
+		if len(f) != 6 {
+			return nil, errors.New(errMsgUndefinedTag)
+		}
+		t := Tag{Name: f[0], Object: f[1], Commit: f[2], Signed: f[4] != "", Subject: f[5]}
+		if t.Commit == "" {
+			// Lightweight tag.
+			t.Commit = t.Object
+		}
+		var sec int64
+		if sec, err = strconv.ParseInt(f[3], 10, 64); err != nil {
+			return nil, err
+		}
+		t.Date = time.Unix(sec, 0)
+		tags = append(tags, t)
+	}
+	return
+}
+
+// latestTag returns the tag of the highest semantic version, or without any of them, the most recent tag.
+func latestTag(tags []Tag) (string, error) {
+	names := make([]string, len(tags))
+	for i, t := range tags {
+		names[i] = t.Name
+	}
+	if sorted := semver.Sort(names); len(sorted) > 0 {
+		return sorted[len(sorted)-1], nil
+	}
+	if len(tags) == 0 {
+		return "", errors.New(errMsgNoTag)
+	}
+	last := tags[0]
+	for _, t := range tags[1:] {
+		if t.Date.After(last.Date) {
+			last = t
+		}
+	}
+	return last.Name, nil
+}
@@ -0,0 +1,145 @@
+package gitflow
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/rvflash/gitup/internal/semver"
+)
+
+// Current is the name of the symbolic link on the worktree of the installed version.
+const Current = "current"
+
+const errMsgInvalidTag = "invalid tag name for a worktree"
+
+// Worktrees installs each version in its own worktree of the repository, named by its tag
+// in the directory of the versions, then switches atomically the symbolic link current on it.
+// The running processes never see a half-updated tree and a version already on disk, like
+// the previous one on rollback, is only switched.
+type Worktrees struct {
+	repo *Repo
+	dir  string
+	keep int
+}
+
+// NewWorktrees returns the worktrees of the repository in the directory of the versions.
+// Keep is the number of worktrees kept on disk with the current one, all of them if zero.
+func NewWorktrees(r *Repo, dir string, keep int) (*Worktrees, error) {
+	if dir = strings.TrimSpace(dir); dir == "" {
+		return nil, errors.New(errMsgUndefinedPath)
+	}
+	dir, err := filepath.Abs(dir)
+	if err != nil {
+		return nil, err
+	}
+	return &Worktrees{repo: r, dir: dir, keep: keep}, nil
+}
+
+// LocalTag returns the version targeted by the symbolic link current.
+// Without link, it returns the version checked out in the repository itself.
+func (w *Worktrees) LocalTag() (string, error) {
+	target, err := os.Readlink(filepath.Join(w.dir, Current))
+	if os.IsNotExist(err) {
+		return w.repo.LocalTag()
+	}
+	if err != nil {
+		return "", err
+	}
+	return filepath.Base(target), nil
+}
+
+// WorkDir returns the symbolic link current, the directory of the installed version.
+func (w *Worktrees) WorkDir() string {
+	return filepath.Join(w.dir, Current)
+}
+
+// CheckoutTag adds the worktree of the tag if necessary, switches the symbolic link current on it,
+// then removes the oldest worktrees beyond the retention.
+func (w *Worktrees) CheckoutTag(tag string) error {
+	if tag = strings.TrimSpace(tag); tag == "" {
+		return errors.New(errMsgUndefinedTag)
+	}
+	if tag != filepath.Base(tag) || strings.HasPrefix(tag, ".") || tag == Current {
+		return errors.New(errMsgInvalidTag)
+	}
+	if _, err := os.Stat(filepath.Join(w.dir, tag)); os.IsNotExist(err) {
+		if err = w.add(tag); err != nil {
+			return err
+		}
+	} else if err != nil {
+		return err
+	}
+	if err := w.link(tag); err != nil {
+		return err
+	}
+	// The version is installed, a failure to prune is only logged.
+	if err := w.prune(tag); err != nil && w.repo.logger != nil {
+		w.repo.logger.Info("worktree pruning failed", "path", w.dir, "error", err)
+	}
+	return nil
+}
+
+// add checks out the tag in a temporary worktree, only moved in place once complete.
+func (w *Worktrees) add(tag string) error {
+	if err := os.MkdirAll(w.dir, 0755); err != nil {
+		return err
+	}
+	tmp := filepath.Join(w.dir, "."+tag)
+	if _, err := os.Stat(tmp); err == nil {
+		// Leftover of an interrupted checkout.
+		os.RemoveAll(tmp)
+		if _, err = w.repo.git("worktree", "prune"); err != nil {
+			return err
+		}
+	}
+	if _, err := w.repo.git("worktree", "add", "--detach", tmp, gitTagFolder+tag); err != nil {
+		return err
+	}
+	_, err := w.repo.git("worktree", "move", tmp, filepath.Join(w.dir, tag))
+	return err
+}
+
+// link switches atomically the symbolic link current on the worktree of the tag.
+func (w *Worktrees) link(tag string) error {
+	tmp := filepath.Join(w.dir, "."+Current)
+	os.Remove(tmp)
+	if err := os.Symlink(tag, tmp); err != nil {
+		return err
+	}
+	return os.Rename(tmp, filepath.Join(w.dir, Current))
+}
+
+// prune removes the worktrees of the oldest versions, except the current one, to only keep the retention.
+func (w *Worktrees) prune(current string) error {
+	if w.keep <= 0 {
+		return nil
+	}
+	entries, err := ioutil.ReadDir(w.dir)
+	if err != nil {
+		return err
+	}
+	var tags []string
+	for _, e := range entries {
+		if e.IsDir() && !strings.HasPrefix(e.Name(), ".") {
+			tags = append(tags, e.Name())
+		}
+	}
+	kept := 1
+	tags = semver.Sort(tags)
+	for i := len(tags) - 1; i >= 0; i-- {
+		if tags[i] == current {
+			continue
+		}
+		if kept < w.keep {
+			kept++
+			continue
+		}
+		if _, err = w.repo.git("worktree", "remove", "--force", filepath.Join(w.dir, tags[i])); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -2,6 +2,7 @@ package semver
 
 import (
 	"errors"
+	"sort"
 	"strconv"
 	"strings"
 )
@@ -121,6 +122,98 @@ func Parse(tag string) (version Version, err error) {
 	return
 }
 
+// Less returns true if the version v has a lower precedence than w.
+// Build metadata is ignored when determining version precedence.
+func (v Version) Less(w Version) bool {
+	return v.precedence(w) < 0
+}
+
+// Sort returns the valid semantic versions of the list, ordered by ascending precedence.
+// Any tag that is not a semantic version is ignored.
+func Sort(tags []string) []string {
+	list := make(byPrecedence, 0, len(tags))
+	for _, tag := range tags {
+		if v, err := Parse(tag); err == nil {
+			list = append(list, taggedVersion{strings.TrimSpace(tag), v})
+		}
+	}
+	sort.Stable(list)
+	res := make([]string, len(list))
+	for i, tv := range list {
+		res[i] = tv.tag
+	}
+	return res
+}
+
+// taggedVersion associates a tag name with its version.
+type taggedVersion struct {
+	tag     string
+	version Version
+}
+
+// byPrecedence implements sort.Interface to order versions by precedence.
+type byPrecedence []taggedVersion
+
+func (p byPrecedence) Len() int           { return len(p) }
+func (p byPrecedence) Less(i, j int) bool { return p[i].version.Less(p[j].version) }
+func (p byPrecedence) Swap(i, j int)      { p[i], p[j] = p[j], p[i] }
+
+// precedence returns -1 if v precedes w, 1 if w precedes v and 0 if they are equals.
+// @see http://semver.org/spec/v2.0.0.html#spec-item-11
+func (v Version) precedence(w Version) int {
+	switch {
+	case v.Major != w.Major:
+		return compareNumber(uint64(v.Major), uint64(w.Major))
+	case v.Minor != w.Minor:
+		return compareNumber(uint64(v.Minor), uint64(w.Minor))
+	case v.Patch != w.Patch:
+		return compareNumber(uint64(v.Patch), uint64(w.Patch))
+	case v.PreRelease == w.PreRelease:
+		return 0
+	case v.PreRelease == "":
+		// A normal version has a higher precedence than a pre-release version.
+		return 1
+	case w.PreRelease == "":
+		return -1
+	}
+	// Compares each dot separated identifier from left to right.
+	pv, pw := strings.Split(v.PreRelease, "."), strings.Split(w.PreRelease, ".")
+	for i := 0; i < len(pv) && i < len(pw); i++ {
+		if c := compareIdentifier(pv[i], pw[i]); c != 0 {
+			return c
+		}
+	}
+	// A larger set of pre-release fields has a higher precedence than a smaller set.
+	return compareNumber(uint64(len(pv)), uint64(len(pw)))
+}
+
+// compareIdentifier compares two pre-release identifiers.
+// Identifiers with digits only are compared numerically, others lexically in ASCII sort order.
+// Numeric identifiers always have lower precedence than the alphanumeric ones.
+func compareIdentifier(a, b string) int {
+	na, ea := strconv.ParseUint(a, 10, 64)
+	nb, eb := strconv.ParseUint(b, 10, 64)
+	switch {
+	case ea == nil && eb == nil:
+		return compareNumber(na, nb)
+	case ea == nil:
+		return -1
+	case eb == nil:
+		return 1
+	}
+	return strings.Compare(a, b)
+}
+
+// compareNumber returns -1 if a is lower than b, 1 if it is greater and 0 otherwise.
+func compareNumber(a, b uint64) int {
+	if a < b {
+		return -1
+	} else if a > b {
+		return 1
+	}
+	return 0
+}
+
 // toVersionNumber returns a uint8 number for a string version.
 func toVersionNumber(version string) (number uint8, err error) {
 	var num uint64
@@ -2,6 +2,7 @@ package semver_test
 
 import (
 	"github.com/rvflash/gitup/internal/semver"
+	"strings"
 	"testing"
 )
 
@@ -63,6 +64,33 @@ var cpOkTests = []struct {
 	{"v1.2.3", "v1.2.3-beta+92", semver.Relationship{0, 0, 0, "<>beta", "<>92", -1}},
 }
 
+var lessTests = []struct {
+	tag1, tag2 string // input
+	less       bool   // expected result
+}{
+	{"v1.2.3", "v1.2.3", false},
+	{"v1.2.3", "v1.2.4", true},
+	{"v1.9.0", "v1.10.0", true},
+	{"v2.0.0", "v1.10.0", false},
+	{"v1.0.0-alpha", "v1.0.0", true},
+	{"v1.0.0", "v1.0.0-alpha", false},
+	{"v1.0.0-alpha", "v1.0.0-alpha.1", true},
+	{"v1.0.0-alpha.1", "v1.0.0-alpha.beta", true},
+	{"v1.0.0-beta.2", "v1.0.0-beta.11", true},
+	{"v1.0.0-rc.1", "v1.0.0-beta.11", false},
+	{"v1.0.0+92", "v1.0.0+1", false},
+}
+
+var sortTests = []struct {
+	tags   []string // input
+	sorted []string // expected result
+}{
+	{nil, []string{}},
+	{[]string{"1.0.0", "master"}, []string{}},
+	{[]string{"v1.10.0", "v1.2.0", "v1.9.1", "v1.9.0"}, []string{"v1.2.0", "v1.9.0", "v1.9.1", "v1.10.0"}},
+	{[]string{"v1.0.0", "v1.0.0-rc.1", "old", "v0.9.0"}, []string{"v0.9.0", "v1.0.0-rc.1", "v1.0.0"}},
+}
+
 // TestCompare tests Compare method with invalid or valid Semantic Version.
 func TestCompare(t *testing.T) {
 	// Checks with various incorrect tags
@@ -102,3 +130,23 @@ func TestParse(t *testing.T) {
 		}
 	}
 }
+
+// TestVersion_Less tests Less method with various versions.
+func TestVersion_Less(t *testing.T) {
+	for _, lt := range lessTests {
+		v1, _ := semver.Parse(lt.tag1)
+		v2, _ := semver.Parse(lt.tag2)
+		if less := v1.Less(v2); less != lt.less {
+			t.Errorf("Expected %t for %v < %v, received: %t", lt.less, lt.tag1, lt.tag2, less)
+		}
+	}
+}
+
+// TestSort tests Sort method with list of valid or invalid tags.
+func TestSort(t *testing.T) {
+	for _, st := range sortTests {
+		if sorted := semver.Sort(st.tags); strings.Join(sorted, ",") != strings.Join(st.sorted, ",") {
+			t.Errorf("Expected %v for %v, received: %v", st.sorted, st.tags, sorted)
+		}
+	}
+}
@@ -0,0 +1,61 @@
+package gitup
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// TestRepo_Ahead tests that an automatic update never moves over local commits.
+func TestRepo_Ahead(t *testing.T) {
+	remote := newRemote(t, "v1.0.0", "v1.1.0")
+	defer os.RemoveAll(remote)
+
+	path := filepath.Join(remote, ".clones", "app")
+	r, err := Clone("file://"+remote, path, UpdateStrategy{}, CloneOptions{Version: "v1.0.0"})
+	if err != nil {
+		t.Fatalf("Expected no error, received: %v", err)
+	}
+	runGit(t, path, nil, "commit", "-q", "--allow-empty", "-m", "local fix")
+
+	s := UpdateStrategy{until: [4]uint8{Auto, Auto, Auto, Auto}}
+	m := NewManager()
+	m.Add("app", r, s)
+	if err = m.Apply("app"); err != nil {
+		t.Fatalf("Expected no error, received: %v", err)
+	}
+	st, _ := m.Status("app")
+	if st.Local != "v1.0.0" || st.Ahead != 1 || r.upStrategy != Manual {
+		t.Errorf("Expected v1.0.0 with one local commit and a manual update, received: %+v", st)
+	}
+	// An operator still moves on the latest version.
+	if err = m.ForceUpdate("app"); err != nil {
+		t.Fatalf("Expected no error, received: %v", err)
+	}
+	if st, _ = m.Status("app"); st.Local != "v1.1.0" || st.Ahead != 0 {
+		t.Errorf("Expected v1.1.0 once forced, received: %+v", st)
+	}
+}
+
+// TestRepo_LocalState tests the local version when HEAD carries several tags.
+func TestRepo_LocalState(t *testing.T) {
+	remote := newRemote(t, "v1.0.0", "v1.2.0")
+	defer os.RemoveAll(remote)
+
+	runGit(t, remote, nil, "tag", "v1.1.9")
+	r, err := NewRepo(remote)
+	if err != nil {
+		t.Fatalf("Expected no error, received: %v", err)
+	}
+	tag, ahead, err := r.localVersion()
+	if err != nil || tag != "v1.2.0" || ahead != 0 {
+		t.Errorf("Expected v1.2.0, received: %q, %d, %v", tag, ahead, err)
+	}
+	ls, ok := r.installs().(LocalStater)
+	if !ok {
+		t.Fatal("Expected the state of a Git repository")
+	}
+	if s, err := ls.LocalState(); err != nil || len(s.Tags) != 2 || s.Tags[1] != "v1.1.9" || !s.OnRelease {
+		t.Errorf("Expected the tags v1.2.0 and v1.1.9 on HEAD, received: %+v, %v", s, err)
+	}
+}
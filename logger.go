@@ -0,0 +1,27 @@
+package gitup
+
+import "github.com/rvflash/gitup/internal/gitflow"
+
+// Logger receives the logs of gitup, as a message with pairs of key and value.
+// It is implemented by *slog.Logger.
+// The Git commands are logged at debug level with their duration, the decisions at info level.
+type Logger interface {
+	Debug(msg string, args ...interface{})
+	Info(msg string, args ...interface{})
+}
+
+// SetLogger defines the logger of the repository and of its Git commands.
+func (r *Repo) SetLogger(l Logger) {
+	r.logger = l
+	if g, ok := r.git.(*gitflow.Repo); ok {
+		g.SetLogger(l)
+	}
+}
+
+// info logs a decision on the repository.
+func (r *Repo) info(msg string, args ...interface{}) {
+	if r.logger == nil {
+		return
+	}
+	r.logger.Info(msg, append([]interface{}{"repo", r.displayName()}, args...)...)
+}
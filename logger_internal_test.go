@@ -0,0 +1,39 @@
+package gitup
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+// FakeLogger records the messages with their key-value pairs.
+type FakeLogger []string
+
+// Debug implements the Logger interface.
+func (l *FakeLogger) Debug(msg string, args ...interface{}) {
+	*l = append(*l, strings.TrimSpace(fmt.Sprintln(append([]interface{}{msg}, args...)...)))
+}
+
+// Info implements the Logger interface.
+func (l *FakeLogger) Info(msg string, args ...interface{}) {
+	l.Debug(msg, args...)
+}
+
+// TestRepo_SetLogger tests that the decisions are logged.
+func TestRepo_SetLogger(t *testing.T) {
+	logs := new(FakeLogger)
+	r := &Repo{git: &FakeStepFlow{FakeGitFlow: FakeGitFlow{localTag: "v1.0.0", remoteTag: "v1.1.0"}}, name: "app"}
+	r.SetLogger(logs)
+	if err := r.Update(UpdateStrategy{until: [4]uint8{Auto, Auto}}); err != nil {
+		t.Fatalf("Expected no error, received: %v", err)
+	}
+	r.Rollback()
+	expected := []string{
+		"update decision repo app local v1.0.0 latest v1.1.0 change minor action auto",
+		"update applied repo app from v1.0.0 to v1.1.0 steps v1.1.0 actor auto",
+		"rolled back repo app from v1.1.0 to v1.0.0",
+	}
+	if strings.Join(*logs, "\n") != strings.Join(expected, "\n") {
+		t.Errorf("Expected logs:\n%s\nreceived:\n%s", strings.Join(expected, "\n"), strings.Join(*logs, "\n"))
+	}
+}
@@ -0,0 +1,264 @@
+package gitup
+
+import (
+	"errors"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Error messages.
+const errMsgUnknownRepo = "unknown repository"
+
+// ErrUnknownRepo is returned when no repository is managed under the name.
+var ErrUnknownRepo = errors.New(errMsgUnknownRepo)
+
+// Manager handles a set of named repositories, each one with its update strategy.
+// It is safe for concurrent use: the operations on a repository are serialized.
+type Manager struct {
+	mu     sync.RWMutex
+	names  []string
+	repos  map[string]*managedRepo
+	events *broker
+}
+
+// managedRepo associates a repository with its update strategy.
+type managedRepo struct {
+	mu       sync.Mutex
+	repo     *Repo
+	strategy UpdateStrategy
+	remote   string
+}
+
+// NewManager returns a new manager without any repository.
+func NewManager() *Manager {
+	return &Manager{repos: make(map[string]*managedRepo), events: newBroker()}
+}
+
+// Add adds the repository under this name with its update strategy.
+func (m *Manager) Add(name string, r *Repo, s UpdateStrategy) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if name = strings.TrimSpace(name); name == "" || m.repos[name] != nil {
+		return errors.New(errMsgRepoName)
+	}
+	r.name = name
+	r.broker().setParent(m.events)
+	mr := &managedRepo{repo: r, strategy: s}
+	if r.git != nil {
+		// Only known for a Git repository.
+		mr.remote, _ = r.git.Remote()
+	}
+	m.names = append(m.names, name)
+	m.repos[name] = mr
+	return nil
+}
+
+// Names returns the names of the repositories, in the order of their addition.
+func (m *Manager) Names() []string {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	return append([]string(nil), m.names...)
+}
+
+// Remotes returns by name the URL of the remote of the Git repositories, as known when they were added.
+// Unlike Statuses, it never waits for the operations in progress.
+func (m *Manager) Remotes() map[string]string {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	res := make(map[string]string, len(m.repos))
+	for name, mr := range m.repos {
+		if mr.remote != "" {
+			res[name] = mr.remote
+		}
+	}
+	return res
+}
+
+// Status returns the current state of the named repository.
+func (m *Manager) Status(name string) (Status, error) {
+	mr, err := m.get(name)
+	if err != nil {
+		return Status{}, err
+	}
+	mr.mu.Lock()
+	defer mr.mu.Unlock()
+
+	return mr.repo.Status(), nil
+}
+
+// Statuses returns the current states of all the repositories.
+func (m *Manager) Statuses() Statuses {
+	names := m.Names()
+	res := make(Statuses, 0, len(names))
+	for _, name := range names {
+		if s, err := m.Status(name); err == nil {
+			res = append(res, s)
+		}
+	}
+	return res
+}
+
+// Check fetches the named repository and returns true if it needs to be updated.
+func (m *Manager) Check(name string) (bool, error) {
+	mr, err := m.get(name)
+	if err != nil {
+		return false, err
+	}
+	mr.mu.Lock()
+	defer mr.mu.Unlock()
+
+	mr.repo.refresh()
+	ok := mr.repo.InDemand(mr.strategy)
+	return ok, mr.repo.lastErr
+}
+
+// Update fetches the named repository and updates it with its strategy.
+// In manual mode, it demands authorisation to the user on the standard input.
+func (m *Manager) Update(name string) error {
+	mr, err := m.get(name)
+	if err != nil {
+		return err
+	}
+	mr.mu.Lock()
+	defer mr.mu.Unlock()
+
+	mr.repo.refresh()
+	return mr.repo.Update(mr.strategy)
+}
+
+// ForceUpdate fetches the named repository and updates it with its strategy, as requested by an operator:
+// a manual update is applied without demanding authorisation and an automatic one without waiting for a maintenance window.
+func (m *Manager) ForceUpdate(name string) error {
+	return m.ForceUpdateAs(name, currentUser())
+}
+
+// ForceUpdateAs is like ForceUpdate, with the actor recorded in the audit log, like "api:10.0.0.1".
+func (m *Manager) ForceUpdateAs(name, actor string) error {
+	mr, err := m.get(name)
+	if err != nil {
+		return err
+	}
+	mr.mu.Lock()
+	defer mr.mu.Unlock()
+
+	mr.repo.refresh()
+	return mr.repo.update(mr.strategy, actor)
+}
+
+// Apply fetches the named repository and applies its strategy without any user interaction:
+// only the automatic updates are applied, the manual ones are only reported by the status.
+func (m *Manager) Apply(name string) error {
+	mr, err := m.get(name)
+	if err != nil {
+		return err
+	}
+	mr.mu.Lock()
+	defer mr.mu.Unlock()
+
+	mr.repo.refresh()
+	if !mr.repo.InDemand(mr.strategy) || mr.repo.upStrategy != Auto {
+		return mr.repo.lastErr
+	}
+	// Reuses the check, to run its hooks and record it only once.
+	if err = mr.repo.updateChecked(mr.strategy, ""); err == ErrDeferred {
+		// Not a failure, the update is queued until the next maintenance window.
+		err = nil
+	}
+	return err
+}
+
+// nextWindow returns the next opening of a maintenance window if the named repository has a deferred update.
+func (m *Manager) nextWindow(name string, t time.Time) time.Time {
+	mr, err := m.get(name)
+	if err != nil {
+		return time.Time{}
+	}
+	mr.mu.Lock()
+	defer mr.mu.Unlock()
+
+	if mr.repo.deferred == "" {
+		return time.Time{}
+	}
+	return mr.strategy.nextWindow(t)
+}
+
+// Rollback moves back the named repository on the version it had before its last update.
+func (m *Manager) Rollback(name string) error {
+	return m.RollbackAs(name, currentUser())
+}
+
+// RollbackAs is like Rollback, with the actor recorded in the audit log.
+func (m *Manager) RollbackAs(name, actor string) error {
+	mr, err := m.get(name)
+	if err != nil {
+		return err
+	}
+	mr.mu.Lock()
+	defer mr.mu.Unlock()
+
+	return mr.repo.rollback(actor)
+}
+
+// inherit carries over the state of the repositories of the previous manager with the same name and path:
+// the result of their last check, the versions of their last update, their counters and the notifications sent.
+// The subscribers to the events of the previous manager keep receiving the ones of this manager.
+func (m *Manager) inherit(old *Manager) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.events = old.events
+	for name, mr := range m.repos {
+		if prev, err := old.get(name); err == nil && prev.repo.path == mr.repo.path {
+			prev.mu.Lock()
+			mr.mu.Lock()
+			mr.repo.inherit(prev.repo)
+			mr.mu.Unlock()
+			prev.mu.Unlock()
+		}
+		mr.repo.broker().setParent(m.events)
+	}
+}
+
+// get returns the named repository.
+func (m *Manager) get(name string) (*managedRepo, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	if mr, ok := m.repos[name]; ok {
+		return mr, nil
+	}
+	return nil, ErrUnknownRepo
+}
+
+// ExportBundle writes in the file a bundle of the named repository, with the tags not reachable from the basis.
+func (m *Manager) ExportBundle(name, file, basis string) (BundleManifest, error) {
+	mr, err := m.get(name)
+	if err != nil {
+		return BundleManifest{}, err
+	}
+	mr.mu.Lock()
+	defer mr.mu.Unlock()
+
+	return mr.repo.ExportBundle(file, basis)
+}
+
+// ImportBundle verifies the bundle with its checksum and the signature of its tags,
+// then updates the named repository with its tags and its strategy.
+// In manual mode, it demands authorisation to the user on the standard input.
+func (m *Manager) ImportBundle(name, file, sum string) error {
+	mr, err := m.get(name)
+	if err != nil {
+		return err
+	}
+	mr.mu.Lock()
+	defer mr.mu.Unlock()
+
+	if err = mr.repo.ImportBundle(file, sum); err != nil {
+		return err
+	}
+	return mr.repo.Update(mr.strategy)
+}
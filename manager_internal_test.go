@@ -0,0 +1,92 @@
+package gitup
+
+import (
+	"context"
+	"testing"
+)
+
+// TestManager_Add tests Add method with valid or invalid names.
+func TestManager_Add(t *testing.T) {
+	m := NewManager()
+	for name, onErr := range map[string]bool{"app": false, " ": true} {
+		if err := m.Add(name, &Repo{}, UpdateStrategy{}); (err != nil) != onErr {
+			t.Errorf("Expected error %t with name '%v', received: %v", onErr, name, err)
+		}
+	}
+	if err := m.Add("app", &Repo{}, UpdateStrategy{}); err == nil {
+		t.Error("Expected error with duplicated name")
+	}
+	m.Add("api", &Repo{}, UpdateStrategy{})
+	if names := m.Names(); len(names) != 2 || names[0] != "app" || names[1] != "api" {
+		t.Errorf("Expected names in order of addition, received: %v", names)
+	}
+}
+
+// TestManager_Apply tests that Apply only performs the automatic updates.
+func TestManager_Apply(t *testing.T) {
+	auto := &FakeStepFlow{FakeGitFlow: FakeGitFlow{localTag: "v1.0.0", remoteTag: "v1.0.1"}}
+	manual := &FakeStepFlow{FakeGitFlow: FakeGitFlow{localTag: "v1.0.0", remoteTag: "v2.0.0"}}
+	failing := &FakeStepFlow{FakeGitFlow: FakeGitFlow{localTag: "v1.0.0", remoteError: true}}
+
+	m := NewManager()
+	m.Add("auto", &Repo{git: auto}, UpdateStrategy{until: [4]uint8{Manual, Manual, Auto}})
+	m.Add("manual", &Repo{git: manual}, UpdateStrategy{until: [4]uint8{Manual, Manual, Auto}})
+	m.Add("failing", &Repo{git: failing}, UpdateStrategy{until: [4]uint8{Auto}})
+
+	if err := m.Apply("auto"); err != nil || len(auto.checkouts) != 1 {
+		t.Errorf("Expected automatic update, received: %v, %v", auto.checkouts, err)
+	}
+	if err := m.Apply("manual"); err != nil || len(manual.checkouts) != 0 {
+		t.Errorf("Expected no manual update, received: %v, %v", manual.checkouts, err)
+	}
+	if err := m.Apply("failing"); err == nil {
+		t.Error("Expected error with failing repository")
+	}
+	if err := m.Apply("unknown"); err == nil {
+		t.Error("Expected error with unknown repository")
+	}
+	if ok, err := m.Check("manual"); !ok || err != nil {
+		t.Errorf("Expected available update, received: %t, %v", ok, err)
+	}
+	if _, err := m.Check("unknown"); err == nil {
+		t.Error("Expected error with unknown repository")
+	}
+	if s := m.Statuses(); len(s) != 3 || s[0].Local != "v1.0.1" || s[2].LastError != errMsgFake {
+		t.Errorf("Expected status of each repository, received: %#v", s)
+	}
+	m.Add("tarball", &Repo{installer: &FakeArtifacts{}}, UpdateStrategy{})
+	if r := m.Remotes(); len(r) != 3 || r["auto"] != remoteURL {
+		t.Errorf("Expected the remote of each Git repository, received: %v", r)
+	}
+}
+
+// TestManager_inherit tests that a new manager carries over the state of the previous one.
+func TestManager_inherit(t *testing.T) {
+	git := &FakeStepFlow{FakeGitFlow: FakeGitFlow{localTag: "v1.0.0", remoteTag: "v1.0.1"}}
+	old := NewManager()
+	old.Add("app", &Repo{git: git, path: "/srv/app"}, UpdateStrategy{until: [4]uint8{Auto}})
+	if err := old.Apply("app"); err != nil {
+		t.Fatalf("Expected no error, received: %v", err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	events := old.Events(ctx)
+
+	m := NewManager()
+	m.Add("app", &Repo{git: git, path: "/srv/app"}, UpdateStrategy{until: [4]uint8{Auto}})
+	m.Add("api", &Repo{git: git, path: "/srv/api"}, UpdateStrategy{})
+	m.inherit(old)
+	if s, _ := m.Status("app"); s.Local != "v1.0.1" || s.CheckedAt.IsZero() {
+		t.Errorf("Expected the state of the last check, received: %#v", s)
+	}
+	mr, _ := m.get("app")
+	if mr.repo.stats.checks != 1 || mr.repo.stats.updates != 1 {
+		t.Errorf("Expected the counters carried over, received: %+v", mr.repo.stats)
+	}
+	if err := m.Rollback("app"); err != nil {
+		t.Fatalf("Expected the rollback of the last update, received: %v", err)
+	}
+	if e, ok := (<-events).(RolledBack); !ok || e.To != "v1.0.0" {
+		t.Errorf("Expected the rollback event on the previous subscriber, received: %#v", e)
+	}
+}
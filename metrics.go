@@ -0,0 +1,173 @@
+package gitup
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/rvflash/gitup/internal/semver"
+)
+
+// durationBuckets are the upper bounds in seconds of the histograms of durations.
+var durationBuckets = [...]float64{0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10, 30, 60}
+
+// histogram counts the observed durations by bucket.
+type histogram struct {
+	counts [len(durationBuckets)]uint64
+	sum    float64
+	count  uint64
+}
+
+// observe adds the duration since the start time.
+func (h *histogram) observe(start time.Time) {
+	d := time.Since(start).Seconds()
+	for i, le := range durationBuckets {
+		if d <= le {
+			h.counts[i]++
+		}
+	}
+	h.sum += d
+	h.count++
+}
+
+// metrics are the counters and the durations measured on a repository.
+type metrics struct {
+	checks, updates, failures, rollbacks uint64
+	fetch, checkout                      histogram
+}
+
+// metricFamily describes a metric in the Prometheus text format.
+type metricFamily struct {
+	name, kind, help string
+}
+
+// Metrics exposed by the manager.
+var (
+	metricUpdateAvailable = metricFamily{"gitup_update_available", "gauge", "Whether a newer version is available, by type of change."}
+	metricVersionInfo     = metricFamily{"gitup_version_info", "gauge", "Local and latest versions of the repository."}
+	metricLastCheck       = metricFamily{"gitup_last_check_timestamp_seconds", "gauge", "Time of the last check of the repository."}
+	metricChecks          = metricFamily{"gitup_checks_total", "counter", "Number of checks."}
+	metricUpdates         = metricFamily{"gitup_updates_total", "counter", "Number of applied updates."}
+	metricFailures        = metricFamily{"gitup_failures_total", "counter", "Number of failed checks, updates and rollbacks."}
+	metricRollbacks       = metricFamily{"gitup_rollbacks_total", "counter", "Number of rollbacks."}
+	metricFetch           = metricFamily{"gitup_fetch_duration_seconds", "histogram", "Duration of the fetches of the latest tag."}
+	metricCheckout        = metricFamily{"gitup_checkout_duration_seconds", "histogram", "Duration of the checkouts of a tag."}
+)
+
+// WriteMetrics writes the state and the metrics of each repository in w, in the Prometheus text format.
+// The counters restart from zero with a new manager, like after a reload of the watcher.
+func (m *Manager) WriteMetrics(w io.Writer) error {
+	type snapshot struct {
+		name, local, latest string
+		kind                int8
+		checkedAt           time.Time
+		stats               metrics
+	}
+	var repos []snapshot
+	for _, name := range m.Names() {
+		mr, err := m.get(name)
+		if err != nil {
+			continue
+		}
+		mr.mu.Lock()
+		r := mr.repo
+		s := snapshot{name: name, local: r.local, latest: r.remote, kind: -1, checkedAt: r.checkedAt, stats: r.stats}
+		if isNewer(r.local, r.remote) {
+			s.kind = changeKind(r.diff)
+		}
+		mr.mu.Unlock()
+		repos = append(repos, s)
+	}
+	bw := bufio.NewWriter(w)
+	metricUpdateAvailable.writeHeader(bw)
+	for _, s := range repos {
+		for k, kind := range kindNames {
+			v := 0.0
+			if int8(k) == s.kind {
+				v = 1
+			}
+			writeSample(bw, metricUpdateAvailable.name, v, "repo", s.name, "kind", kind)
+		}
+	}
+	metricVersionInfo.writeHeader(bw)
+	for _, s := range repos {
+		writeSample(bw, metricVersionInfo.name, 1, "repo", s.name, "local", s.local, "latest", s.latest)
+	}
+	metricLastCheck.writeHeader(bw)
+	for _, s := range repos {
+		if !s.checkedAt.IsZero() {
+			writeSample(bw, metricLastCheck.name, float64(s.checkedAt.Unix()), "repo", s.name)
+		}
+	}
+	for _, c := range []struct {
+		metricFamily
+		value func(m metrics) uint64
+	}{
+		{metricChecks, func(m metrics) uint64 { return m.checks }},
+		{metricUpdates, func(m metrics) uint64 { return m.updates }},
+		{metricFailures, func(m metrics) uint64 { return m.failures }},
+		{metricRollbacks, func(m metrics) uint64 { return m.rollbacks }},
+	} {
+		c.writeHeader(bw)
+		for _, s := range repos {
+			writeSample(bw, c.name, float64(c.value(s.stats)), "repo", s.name)
+		}
+	}
+	for _, h := range []struct {
+		metricFamily
+		value func(m metrics) histogram
+	}{
+		{metricFetch, func(m metrics) histogram { return m.fetch }},
+		{metricCheckout, func(m metrics) histogram { return m.checkout }},
+	} {
+		h.writeHeader(bw)
+		for _, s := range repos {
+			hs := h.value(s.stats)
+			for i, le := range durationBuckets {
+				writeSample(bw, h.name+"_bucket", float64(hs.counts[i]), "repo", s.name, "le", formatFloat(le))
+			}
+			writeSample(bw, h.name+"_bucket", float64(hs.count), "repo", s.name, "le", "+Inf")
+			writeSample(bw, h.name+"_sum", hs.sum, "repo", s.name)
+			writeSample(bw, h.name+"_count", float64(hs.count), "repo", s.name)
+		}
+	}
+	return bw.Flush()
+}
+
+// writeHeader writes the help and the type of the metric.
+func (f metricFamily) writeHeader(w io.Writer) {
+	fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s %s\n", f.name, f.help, f.name, f.kind)
+}
+
+// writeSample writes a sample of the metric with its labels, given as pairs of name and value.
+func writeSample(w io.Writer, name string, value float64, labels ...string) {
+	pairs := make([]string, 0, len(labels)/2)
+	for i := 0; i+1 < len(labels); i += 2 {
+		pairs = append(pairs, labels[i]+`="`+labelEscaper.Replace(labels[i+1])+`"`)
+	}
+	fmt.Fprintf(w, "%s{%s} %s\n", name, strings.Join(pairs, ","), formatFloat(value))
+}
+
+// labelEscaper escapes a label value as expected by the Prometheus text format.
+var labelEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+// isNewer returns true if the latest version has a higher precedence than the local one.
+func isNewer(local, latest string) bool {
+	lv, err := semver.Parse(local)
+	if err != nil {
+		return false
+	}
+	rv, err := semver.Parse(latest)
+	if err != nil {
+		return false
+	}
+	return lv.Less(rv)
+}
+
+// formatFloat returns the shortest representation of the value.
+func formatFloat(v float64) string {
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
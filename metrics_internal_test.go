@@ -0,0 +1,65 @@
+package gitup
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+// TestManager_WriteMetrics tests the state, the counters and the durations of the repositories.
+func TestManager_WriteMetrics(t *testing.T) {
+	app := &FakeStepFlow{FakeGitFlow: FakeGitFlow{localTag: "v1.0.0", remoteTag: "v1.0.1"}}
+	api := &FakeStepFlow{FakeGitFlow: FakeGitFlow{localTag: "v1.0.0", remoteTag: "v1.1.0"}}
+	failing := &FakeStepFlow{FakeGitFlow: FakeGitFlow{localTag: "v1.0.0", remoteError: true}}
+	web := &FakeStepFlow{FakeGitFlow: FakeGitFlow{localTag: "v1.9.0", remoteTag: "v1.10.0"}}
+
+	m := NewManager()
+	m.Add("app", &Repo{git: app}, UpdateStrategy{until: [4]uint8{Auto}})
+	m.Add("api", &Repo{git: api}, UpdateStrategy{until: [4]uint8{Manual}})
+	m.Add("failing", &Repo{git: failing}, UpdateStrategy{until: [4]uint8{Auto}})
+	m.Add("web", &Repo{git: web}, UpdateStrategy{until: [4]uint8{Manual}})
+
+	m.Apply("app")
+	m.Rollback("app")
+	m.Check("api")
+	m.Apply("failing")
+	m.Check("web")
+
+	var buf bytes.Buffer
+	if err := m.WriteMetrics(&buf); err != nil {
+		t.Fatalf("Expected no error, received: %v", err)
+	}
+	out := buf.String()
+	for _, line := range []string{
+		"# TYPE gitup_update_available gauge\n",
+		`gitup_update_available{repo="app",kind="patch"} 1` + "\n",
+		`gitup_update_available{repo="api",kind="minor"} 1` + "\n",
+		`gitup_update_available{repo="api",kind="patch"} 0` + "\n",
+		`gitup_update_available{repo="web",kind="minor"} 1` + "\n",
+		`gitup_version_info{repo="app",local="v1.0.0",latest="v1.0.1"} 1` + "\n",
+		`gitup_version_info{repo="failing",local="v1.0.0",latest=""} 1` + "\n",
+		`gitup_checks_total{repo="app"} 1` + "\n",
+		`gitup_updates_total{repo="app"} 1` + "\n",
+		`gitup_rollbacks_total{repo="app"} 1` + "\n",
+		`gitup_failures_total{repo="failing"} 1` + "\n",
+		`gitup_failures_total{repo="app"} 0` + "\n",
+		"# TYPE gitup_fetch_duration_seconds histogram\n",
+		`gitup_fetch_duration_seconds_bucket{repo="api",le="+Inf"} 1` + "\n",
+		`gitup_checkout_duration_seconds_bucket{repo="app",le="60"} 2` + "\n",
+		`gitup_checkout_duration_seconds_count{repo="app"} 2` + "\n",
+		`gitup_checkout_duration_seconds_count{repo="api"} 0` + "\n",
+	} {
+		if !strings.Contains(out, line) {
+			t.Errorf("Expected %q in metrics, received:\n%s", line, out)
+		}
+	}
+}
+
+// TestWriteSample tests the escaping of the label values.
+func TestWriteSample(t *testing.T) {
+	var buf bytes.Buffer
+	writeSample(&buf, "gitup_version_info", 1, "repo", "a\\b\"c\nd\té")
+	if exp := `gitup_version_info{repo="a\\b\"c\nd` + "\té\"} 1\n"; buf.String() != exp {
+		t.Errorf("Expected %q, received: %q", exp, buf.String())
+	}
+}
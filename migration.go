@@ -0,0 +1,214 @@
+package gitup
+
+import (
+	"bufio"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/rvflash/gitup/internal/semver"
+)
+
+// Migration settings.
+const (
+	migrationSep     = "_"
+	migrationLogName = "gitup_migrations"
+	envOldVersion    = "GITUP_OLD_VERSION"
+	envNewVersion    = "GITUP_NEW_VERSION"
+)
+
+// MigrationError is returned when a migration script fails.
+type MigrationError struct {
+	Script string
+	Err    error
+}
+
+// Error implements the error interface.
+func (e *MigrationError) Error() string {
+	return "migration " + e.Script + " failed: " + e.Err.Error()
+}
+
+// migration represents a script to apply for a given version.
+type migration struct {
+	name    string
+	version semver.Version
+}
+
+// byVersion implements sort.Interface to order migrations by version, then by name.
+type byVersion []migration
+
+func (p byVersion) Len() int      { return len(p) }
+func (p byVersion) Swap(i, j int) { p[i], p[j] = p[j], p[i] }
+func (p byVersion) Less(i, j int) bool {
+	if p[i].version.Less(p[j].version) {
+		return true
+	}
+	if p[j].version.Less(p[i].version) {
+		return false
+	}
+	return p[i].name < p[j].name
+}
+
+// Enable testing by mocking *exec.Cmd.
+var execCommand = exec.Command
+
+// SetMigrationDir enables the migration scripts stored in this directory, relative to the root of the installed version.
+// Each script is named by the version it belongs to, followed by an underscore, like v1.4.0_add_index.sh.
+// After each checkout, the scripts of the versions above the old one and no higher than the new one run in order.
+// The names of applied scripts are recorded in the Git directory, or without it, in the file .gitup_migrations
+// of the repository's root. An empty directory disables migrations.
+func (r *Repo) SetMigrationDir(dir string) {
+	r.migrationDir = strings.TrimSpace(dir)
+}
+
+// Migrations returns the names of migration scripts already applied.
+func (r *Repo) Migrations() ([]string, error) {
+	log, err := r.migrationLogPath()
+	if err != nil {
+		return nil, err
+	}
+	done, err := appliedMigrations(log)
+	if err != nil {
+		return nil, err
+	}
+	names := make([]string, 0, len(done))
+	for name := range done {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names, nil
+}
+
+// migrate runs in order the migration scripts not yet applied between the two versions.
+func (r *Repo) migrate(from, to string) error {
+	if r.migrationDir == "" {
+		return nil
+	}
+	list, err := r.migrations(from, to)
+	if err != nil {
+		return err
+	}
+	// The log is resolved before running any script, to always record them.
+	log, err := r.migrationLogPath()
+	if err != nil {
+		return err
+	}
+	done, err := appliedMigrations(log)
+	if err != nil {
+		return err
+	}
+	dir := r.workDir()
+	for _, name := range list {
+		if done[name] {
+			continue
+		}
+		cmd := execCommand(filepath.Join(dir, r.migrationDir, name))
+		cmd.Dir = dir
+		if cmd.Env == nil {
+			cmd.Env = os.Environ()
+		}
+		cmd.Env = append(cmd.Env, envOldVersion+"="+from, envNewVersion+"="+to)
+		if err = cmd.Run(); err != nil {
+			return &MigrationError{Script: name, Err: err}
+		}
+		if err = recordMigration(log, name); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// migrations returns in order the scripts belonging to a version above from and no higher than to.
+func (r *Repo) migrations(from, to string) ([]string, error) {
+	fv, err := semver.Parse(from)
+	if err != nil {
+		return nil, err
+	}
+	tv, err := semver.Parse(to)
+	if err != nil {
+		return nil, err
+	}
+	files, err := ioutil.ReadDir(filepath.Join(r.workDir(), r.migrationDir))
+	if err != nil {
+		if os.IsNotExist(err) {
+			// No migration for this version.
+			err = nil
+		}
+		return nil, err
+	}
+	var list byVersion
+	for _, f := range files {
+		if f.IsDir() {
+			continue
+		}
+		v, err := semver.Parse(strings.SplitN(f.Name(), migrationSep, 2)[0])
+		if err != nil || !fv.Less(v) || tv.Less(v) {
+			continue
+		}
+		list = append(list, migration{f.Name(), v})
+	}
+	sort.Stable(list)
+	names := make([]string, len(list))
+	for i, m := range list {
+		names[i] = m.name
+	}
+	return names, nil
+}
+
+// appliedMigrations returns the scripts already applied, read from the migration log.
+func appliedMigrations(log string) (map[string]bool, error) {
+	done := make(map[string]bool)
+	f, err := os.Open(log)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return done, nil
+		}
+		return nil, err
+	}
+	defer f.Close()
+
+	input := bufio.NewScanner(f)
+	for input.Scan() {
+		if name := strings.TrimSpace(input.Text()); name != "" {
+			done[name] = true
+		}
+	}
+	return done, input.Err()
+}
+
+// recordMigration appends the name of the script to the migration log.
+func recordMigration(log, name string) error {
+	f, err := os.OpenFile(log, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+	if _, err = f.WriteString(name + "\n"); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// migrationLogPath returns the path of the file listing the applied migrations: in the Git directory,
+// shared by the linked worktrees, or without Git, in the repository's root.
+func (r *Repo) migrationLogPath() (string, error) {
+	if r.migrationLog != "" {
+		return r.migrationLog, nil
+	}
+	if g, ok := r.git.(gitDirer); ok {
+		dir, err := g.GitDir()
+		if err != nil {
+			return "", err
+		}
+		return filepath.Join(dir, migrationLogName), nil
+	}
+	return filepath.Join(r.path, "."+migrationLogName), nil
+}
+
+// gitDirer is implemented by the Git repositories, to locate their Git directory.
+type gitDirer interface {
+	GitDir() (string, error)
+}
@@ -0,0 +1,218 @@
+package gitup
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const failScript = "fail"
+
+var migrationTests = []struct {
+	from, to string // input
+	scripts  string // expected result
+	onErr    bool
+}{
+	{"v1.3.0", "v1.3.0", "", false},
+	{"v1.3.0", "v1.4.0", "v1.4.0_a.sh,v1.4.0_b.sh", false},
+	{"v1.3.0", "v1.6.0", "v1.4.0_a.sh,v1.4.0_b.sh,v1.6.0_c.sh", false},
+	{"v1.4.0", "v1.6.0", "v1.6.0_c.sh", false},
+	{"v1.6.0", "v1.10.0", "v1.7.0_d.sh,v1.10.0_e.sh", false},
+	{"v1.6", "v1.10.0", "", true},
+}
+
+// fakeExecCommand returns a mock of the exec command.
+func fakeExecCommand(command string, args ...string) *exec.Cmd {
+	cs := []string{"-test.run=TestHelperProcess", "--", command}
+	cs = append(cs, args...)
+	cmd := exec.Command(os.Args[0], cs...)
+	cmd.Env = []string{"GO_WANT_HELPER_PROCESS=1"}
+	return cmd
+}
+
+// fakeMigrationDir returns a temporary repository with various migration scripts.
+func fakeMigrationDir(scripts ...string) (dir string, err error) {
+	if dir, err = ioutil.TempDir(os.TempDir(), "repo"); err != nil {
+		return
+	}
+	if err = os.MkdirAll(filepath.Join(dir, "migrations"), 0755); err != nil {
+		return
+	}
+	for _, name := range scripts {
+		if err = ioutil.WriteFile(filepath.Join(dir, "migrations", name), []byte("#!/bin/sh\n"), 0755); err != nil {
+			return
+		}
+	}
+	return
+}
+
+// TestRepo_Migrations tests the listing of migration scripts between two versions.
+func TestRepo_Migrations(t *testing.T) {
+	dir, err := fakeMigrationDir(
+		"v1.10.0_e.sh", "v1.3.0_a.sh", "v1.4.0_b.sh", "v1.4.0_a.sh", "v1.6.0_c.sh", "v1.7.0_d.sh", "README.md",
+	)
+	if err != nil {
+		t.Fatalf("Unable to create migration directory, received error: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	r := &Repo{path: dir}
+	r.SetMigrationDir("migrations")
+	for _, mt := range migrationTests {
+		if list, err := r.migrations(mt.from, mt.to); err != nil {
+			if !mt.onErr {
+				t.Errorf("Expected no error from %v to %v, received: %v", mt.from, mt.to, err)
+			}
+		} else if mt.onErr {
+			t.Errorf("Expected error from %v to %v", mt.from, mt.to)
+		} else if scripts := strings.Join(list, ","); scripts != mt.scripts {
+			t.Errorf("Expected scripts %v from %v to %v, received: %v", mt.scripts, mt.from, mt.to, scripts)
+		}
+	}
+	// Without migration directory.
+	r.SetMigrationDir("unknown")
+	if list, err := r.migrations("v1.3.0", "v1.6.0"); err != nil || len(list) != 0 {
+		t.Errorf("Expected no migration without directory, received: %v, %v", list, err)
+	}
+}
+
+// TestRepo_migrate tests the run of the migration scripts and the record of the applied ones.
+func TestRepo_migrate(t *testing.T) {
+	execCommand = fakeExecCommand
+
+	// Restore exec command behavior at the end of the test.
+	defer func() { execCommand = exec.Command }()
+
+	dir, err := fakeMigrationDir("v1.4.0_a.sh", "v1.5.0_b.sh", "v1.6.0_"+failScript+".sh")
+	if err != nil {
+		t.Fatalf("Unable to create migration directory, received error: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	r := &Repo{path: dir, migrationLog: filepath.Join(dir, migrationLogName)}
+	if err := r.migrate("v1.3.0", "v1.6.0"); err != nil {
+		t.Errorf("Expected no error with migrations disabled, received: %v", err)
+	}
+	r.SetMigrationDir("migrations")
+	if err := r.migrate("v1.3.0", "v1.5.0"); err != nil {
+		t.Errorf("Expected no error, received: %v", err)
+	}
+	err = r.migrate("v1.3.0", "v1.6.0")
+	if me, ok := err.(*MigrationError); !ok || me.Script != "v1.6.0_"+failScript+".sh" {
+		t.Errorf("Expected migration error on the failing script, received: %v", err)
+	}
+	if done, err := r.Migrations(); err != nil {
+		t.Errorf("Expected no error, received: %v", err)
+	} else if scripts := strings.Join(done, ","); scripts != "v1.4.0_a.sh,v1.5.0_b.sh" {
+		t.Errorf("Expected applied scripts, received: %v", scripts)
+	}
+}
+
+// FakeInstalledArtifacts extends FakeArtifacts to install the versions outside the repository's root.
+type FakeInstalledArtifacts struct {
+	FakeArtifacts
+	dir string
+}
+
+// WorkDir returns the directory of the installed version.
+func (a *FakeInstalledArtifacts) WorkDir() string {
+	return a.dir
+}
+
+// TestRepo_migrate_WorkDir tests that the migrations and the hooks run in the directory of the installed version.
+func TestRepo_migrate_WorkDir(t *testing.T) {
+	dir, err := ioutil.TempDir(os.TempDir(), "gitup")
+	if err != nil {
+		t.Fatalf("Unable to create directory, received: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	script := filepath.Join(dir, "v1.1.0", "migrations", "v1.1.0_touch.sh")
+	if err = os.MkdirAll(filepath.Dir(script), 0755); err != nil {
+		t.Fatalf("Unable to create directory, received: %v", err)
+	}
+	if err = ioutil.WriteFile(script, []byte("#!/bin/sh\ntouch migrated\n"), 0755); err != nil {
+		t.Fatalf("Unable to write the script, received: %v", err)
+	}
+	r := &Repo{path: dir, installer: &FakeInstalledArtifacts{dir: filepath.Join(dir, "current")}}
+	r.SetMigrationDir("migrations")
+	var paths []string
+	r.AddHook(BeforeUpdate, HookFunc(func(e HookEvent) error {
+		paths = append(paths, e.Path)
+		return nil
+	}))
+	// Without installed version, the hooks run in the repository's root.
+	r.runHooks(BeforeUpdate, "", "v1.1.0")
+	if err = os.Symlink("v1.1.0", filepath.Join(dir, "current")); err != nil {
+		t.Fatalf("Unable to link the version, received: %v", err)
+	}
+	r.runHooks(BeforeUpdate, "v1.0.0", "v1.1.0")
+	if len(paths) != 2 || paths[0] != dir || paths[1] != filepath.Join(dir, "current") {
+		t.Errorf("Expected the hooks in the repository's root, then in the installed version, received: %v", paths)
+	}
+	if err = r.migrate("v1.0.0", "v1.1.0"); err != nil {
+		t.Fatalf("Expected no error, received: %v", err)
+	}
+	if _, err = os.Stat(filepath.Join(dir, "v1.1.0", "migrated")); err != nil {
+		t.Errorf("Expected the migration run in the installed version, received: %v", err)
+	}
+	if done, err := r.Migrations(); err != nil || len(done) != 1 {
+		t.Errorf("Expected the migration recorded, received: %v, %v", done, err)
+	}
+	// The notifier commands run there too.
+	r.AddNotifier(Command("touch notified"))
+	r.notify(NotifyApplied, "v1.0.0", "v1.1.0", nil)
+	if _, err = os.Stat(filepath.Join(dir, "v1.1.0", "notified")); err != nil {
+		t.Errorf("Expected the notifier run in the installed version, received: %v", err)
+	}
+}
+
+// TestRepo_migrationLogPath tests the location of the migration log, with or without Git.
+func TestRepo_migrationLogPath(t *testing.T) {
+	r := &Repo{path: "/srv/app", installer: &FakeArtifacts{}}
+	if log, err := r.migrationLogPath(); err != nil || log != "/srv/app/."+migrationLogName {
+		t.Errorf("Expected the log in the repository's root without Git, received: %v, %v", log, err)
+	}
+	remote := newRemote(t, "v1.0.0")
+	defer os.RemoveAll(remote)
+
+	path := filepath.Join(remote, ".linked")
+	runGit(t, remote, nil, "worktree", "add", "-q", "--detach", path, "v1.0.0")
+	g, err := NewRepo(path)
+	if err != nil {
+		t.Fatalf("Expected no error, received: %v", err)
+	}
+	if log, err := g.migrationLogPath(); err != nil || log != filepath.Join(remote, ".git", migrationLogName) {
+		t.Errorf("Expected the log in the common Git directory of the worktree, received: %v, %v", log, err)
+	}
+}
+
+// TestHelperProcess mocks exec commands and responds instead of the scripts and commands.
+func TestHelperProcess(*testing.T) {
+	if os.Getenv("GO_WANT_HELPER_PROCESS") != "1" {
+		return
+	}
+	defer os.Exit(0)
+
+	// Extract only exec arguments.
+	args := os.Args
+	for len(args) > 0 {
+		if args[0] == "--" {
+			args = args[1:]
+			break
+		}
+		args = args[1:]
+	}
+	if len(args) == 0 {
+		fmt.Fprintf(os.Stderr, "No command\n")
+		os.Exit(2)
+	}
+	if strings.Contains(filepath.Base(strings.Join(args, " ")), failScript) {
+		fmt.Fprintf(os.Stderr, "fatal: command failed\n")
+		os.Exit(1)
+	}
+}
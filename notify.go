@@ -0,0 +1,245 @@
+package gitup
+
+import (
+	"bytes"
+	"crypto/tls"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"net/smtp"
+	"os"
+	"strings"
+	"time"
+)
+
+// Notified changes.
+const (
+	NotifyAvailable = "available"
+	NotifyApplied   = "applied"
+	NotifyFailed    = "failed"
+)
+
+// Notification settings.
+const (
+	errMsgNotifyStatus = "unexpected status code of the notification"
+	envNotifyEvent     = "GITUP_EVENT"
+	envRepoName        = "GITUP_REPO"
+)
+
+// NotifyTimeout is the maximum duration of a notification sent by email or to a webhook without its own client.
+// The notifications are sent while the repository is locked, a notifier must never hang.
+var NotifyTimeout = 10 * time.Second
+
+// Notification describes an update available, applied or failed on a repository.
+type Notification struct {
+	Event string    `json:"event"`
+	Repo  string    `json:"repo"`
+	Path  string    `json:"path"`
+	From  string    `json:"from"`
+	To    string    `json:"to"`
+	Err   string    `json:"error,omitempty"`
+	Time  time.Time `json:"time"`
+	// dir is the directory of the installed version, where the commands run like the hooks.
+	dir string
+}
+
+// String implements the fmt.Stringer interface.
+func (n Notification) String() string {
+	switch n.Event {
+	case NotifyAvailable:
+		return fmt.Sprintf("%s: %s is available, currently on %s", n.Repo, n.To, n.From)
+	case NotifyApplied:
+		return fmt.Sprintf("%s: updated from %s to %s", n.Repo, n.From, n.To)
+	}
+	return fmt.Sprintf("%s: update from %s to %s failed: %s", n.Repo, n.From, n.To, n.Err)
+}
+
+// Notifier is called when an update becomes available, is applied or fails.
+type Notifier interface {
+	Notify(n Notification) error
+}
+
+// NotifierFunc is an adapter to use an ordinary function as Notifier.
+type NotifierFunc func(n Notification) error
+
+// Notify implements the Notifier interface.
+func (f NotifierFunc) Notify(n Notification) error {
+	return f(n)
+}
+
+// Webhook posts the notification as JSON to its URL.
+// Without client, the request is limited by NotifyTimeout.
+type Webhook struct {
+	URL    string
+	Client *http.Client
+}
+
+// Notify implements the Notifier interface.
+func (w Webhook) Notify(n Notification) error {
+	return postJSON(w.Client, w.URL, n)
+}
+
+// Slack posts the notification as message to a Slack-compatible incoming webhook.
+// Without client, the request is limited by NotifyTimeout.
+type Slack struct {
+	URL    string
+	Client *http.Client
+}
+
+// Notify implements the Notifier interface.
+func (s Slack) Notify(n Notification) error {
+	return postJSON(s.Client, s.URL, struct {
+		Text string `json:"text"`
+	}{n.String()})
+}
+
+// Mail sends the notification by email with the SMTP server at Addr, like "smtp.example.com:25".
+// Auth is optional. The whole session is limited by NotifyTimeout.
+type Mail struct {
+	Addr string
+	Auth smtp.Auth
+	From string
+	To   []string
+}
+
+// Notify implements the Notifier interface.
+func (m Mail) Notify(n Notification) error {
+	// The subject includes the error, on a single line to never add any header.
+	subject := headerEscaper.Replace(n.String())
+	msg := fmt.Sprintf("From: %s\r\nTo: %s\r\nSubject: [gitup] %s\r\n\r\n%s\r\n",
+		m.From, strings.Join(m.To, ", "), subject, n.String())
+	return m.send([]byte(msg))
+}
+
+// headerEscaper replaces the line breaks of a header value.
+var headerEscaper = strings.NewReplacer("\r\n", " ", "\r", " ", "\n", " ")
+
+// send sends the message like smtp.SendMail, but with a deadline on the connection.
+func (m Mail) send(msg []byte) error {
+	conn, err := net.DialTimeout("tcp", m.Addr, NotifyTimeout)
+	if err != nil {
+		return err
+	}
+	if err = conn.SetDeadline(time.Now().Add(NotifyTimeout)); err != nil {
+		conn.Close()
+		return err
+	}
+	host, _, _ := net.SplitHostPort(m.Addr)
+	c, err := smtp.NewClient(conn, host)
+	if err != nil {
+		conn.Close()
+		return err
+	}
+	defer c.Close()
+	if ok, _ := c.Extension("STARTTLS"); ok {
+		if err = c.StartTLS(&tls.Config{ServerName: host}); err != nil {
+			return err
+		}
+	}
+	if m.Auth != nil {
+		if err = c.Auth(m.Auth); err != nil {
+			return err
+		}
+	}
+	if err = c.Mail(m.From); err != nil {
+		return err
+	}
+	for _, to := range m.To {
+		if err = c.Rcpt(to); err != nil {
+			return err
+		}
+	}
+	w, err := c.Data()
+	if err != nil {
+		return err
+	}
+	if _, err = w.Write(msg); err != nil {
+		return err
+	}
+	if err = w.Close(); err != nil {
+		return err
+	}
+	return c.Quit()
+}
+
+// Notify implements the Notifier interface. The command runs in the directory of the installed version,
+// like the hooks, or else in the repository's one, and receives
+// the notification with the environment variables GITUP_EVENT, GITUP_REPO, GITUP_OLD_VERSION,
+// GITUP_NEW_VERSION and on failure, GITUP_ERROR.
+func (c Command) Notify(n Notification) error {
+	cmd := execCommand("sh", "-c", string(c))
+	cmd.Dir = n.dir
+	if cmd.Dir == "" {
+		cmd.Dir = n.Path
+	}
+	if cmd.Env == nil {
+		cmd.Env = os.Environ()
+	}
+	cmd.Env = append(cmd.Env,
+		envNotifyEvent+"="+n.Event,
+		envRepoName+"="+n.Repo,
+		envOldVersion+"="+n.From,
+		envNewVersion+"="+n.To,
+	)
+	if n.Err != "" {
+		cmd.Env = append(cmd.Env, envError+"="+n.Err)
+	}
+	return cmd.Run()
+}
+
+// postJSON posts the value as JSON to the URL and expects a successful status code.
+func postJSON(client *http.Client, url string, v interface{}) error {
+	buf, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	if client == nil {
+		client = &http.Client{Timeout: NotifyTimeout}
+	}
+	resp, err := client.Post(url, "application/json", bytes.NewReader(buf))
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("%s: %d", errMsgNotifyStatus, resp.StatusCode)
+	}
+	return nil
+}
+
+// AddNotifier adds a notifier of the updates available, applied or failed on the repository.
+// Each notifier is called once by event and version, like once for "v2.0.0 available",
+// and for the failures, once by error.
+func (r *Repo) AddNotifier(n Notifier) {
+	r.notifiers = append(r.notifiers, n)
+}
+
+// notify calls each notifier with the event, if it has not already been notified for this version.
+// It deliberately ignores the errors of the notifiers, which never block the updates.
+func (r *Repo) notify(event, from, to string, err error) {
+	if len(r.notifiers) == 0 {
+		return
+	}
+	key := event + "@" + to
+	if err != nil {
+		// Another failure on the same version is notified too.
+		key += ": " + err.Error()
+	}
+	if r.notified[key] {
+		return
+	}
+	if r.notified == nil {
+		r.notified = make(map[string]bool)
+	}
+	r.notified[key] = true
+	n := Notification{Event: event, Repo: r.displayName(), Path: r.path, From: from, To: to, Time: time.Now(), dir: r.workDir()}
+	if err != nil {
+		n.Err = err.Error()
+	}
+	for _, nt := range r.notifiers {
+		if err := nt.Notify(n); err != nil {
+			r.info("notification failed", "event", event, "to", to, "error", err)
+		}
+	}
+}
@@ -0,0 +1,222 @@
+package gitup
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/textproto"
+	"os"
+	"os/exec"
+	"strings"
+	"testing"
+	"time"
+)
+
+// TestRepo_AddNotifier tests that each event is notified once by version.
+func TestRepo_AddNotifier(t *testing.T) {
+	var got []string
+	git := &FakeStepFlow{FakeGitFlow: FakeGitFlow{localTag: "v1.0.0", remoteTag: "v2.0.0"}}
+	r := &Repo{git: git, name: "app"}
+	r.AddNotifier(NotifierFunc(func(n Notification) error {
+		got = append(got, n.String())
+		return nil
+	}))
+	s := UpdateStrategy{until: [4]uint8{Manual}}
+	for i := 0; i < 3; i++ {
+		r.refresh()
+		r.InDemand(s)
+	}
+	// Applied then failed on the next version.
+	r.Update(UpdateStrategy{until: [4]uint8{Auto}})
+	r.git = FakeGitFlow{localTag: "v2.0.0", remoteTag: "v2.1.0", checkoutError: true}
+	for i := 0; i < 2; i++ {
+		r.refresh()
+		r.Update(UpdateStrategy{until: [4]uint8{Auto, Auto}})
+	}
+	r.notify(NotifyFailed, "v2.0.0", "v2.1.0", errors.New("disk full"))
+	expected := []string{
+		"app: v2.0.0 is available, currently on v1.0.0",
+		"app: updated from v1.0.0 to v2.0.0",
+		"app: v2.1.0 is available, currently on v2.0.0",
+		"app: update from v2.0.0 to v2.1.0 failed: " + errMsgFake,
+		"app: update from v2.0.0 to v2.1.0 failed: disk full",
+	}
+	if strings.Join(got, "\n") != strings.Join(expected, "\n") {
+		t.Errorf("Expected notifications:\n%s\nreceived:\n%s", strings.Join(expected, "\n"), strings.Join(got, "\n"))
+	}
+}
+
+// TestWebhook_Notify tests the JSON webhook and the Slack notifier with a local HTTP server.
+func TestWebhook_Notify(t *testing.T) {
+	var bodies []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		buf, _ := ioutil.ReadAll(r.Body)
+		bodies = append(bodies, string(buf))
+		if r.URL.Path == "/fail" {
+			w.WriteHeader(http.StatusInternalServerError)
+		}
+	}))
+	defer srv.Close()
+
+	n := Notification{Event: NotifyApplied, Repo: "app", From: "v1.0.0", To: "v1.1.0"}
+	if err := (Webhook{URL: srv.URL}).Notify(n); err != nil {
+		t.Errorf("Expected no error, received: %v", err)
+	}
+	if err := (Slack{URL: srv.URL}).Notify(n); err != nil {
+		t.Errorf("Expected no error, received: %v", err)
+	}
+	if err := (Webhook{URL: srv.URL + "/fail"}).Notify(n); err == nil {
+		t.Error("Expected error with failing webhook")
+	}
+	var res Notification
+	if len(bodies) != 3 || json.Unmarshal([]byte(bodies[0]), &res) != nil || res.To != "v1.1.0" {
+		t.Fatalf("Expected notification as JSON, received: %v", bodies)
+	}
+	if bodies[1] != `{"text":"app: updated from v1.0.0 to v1.1.0"}` {
+		t.Errorf("Expected Slack message, received: %v", bodies[1])
+	}
+}
+
+// fakeSMTP serves a single SMTP session and returns the received message.
+func fakeSMTP(t *testing.T) (addr string, msg chan string) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Unable to listen, received: %v", err)
+	}
+	msg = make(chan string, 1)
+	go func() {
+		defer l.Close()
+		conn, err := l.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		tp := textproto.NewConn(conn)
+		tp.PrintfLine("220 localhost ESMTP")
+		for {
+			line, err := tp.ReadLine()
+			if err != nil {
+				return
+			}
+			switch cmd := strings.ToUpper(strings.Fields(line + " ")[0]); cmd {
+			case "EHLO", "HELO":
+				tp.PrintfLine("250 localhost")
+			case "DATA":
+				tp.PrintfLine("354 end with .")
+				buf, _ := tp.ReadDotBytes()
+				msg <- string(buf)
+				tp.PrintfLine("250 OK")
+			case "QUIT":
+				tp.PrintfLine("221 bye")
+				return
+			default:
+				tp.PrintfLine("250 OK")
+			}
+		}
+	}()
+	return l.Addr().String(), msg
+}
+
+// TestMail_Notify tests the email notifier with a local SMTP server.
+func TestMail_Notify(t *testing.T) {
+	addr, msg := fakeSMTP(t)
+	n := Notification{Event: NotifyAvailable, Repo: "app", From: "v1.0.0", To: "v2.0.0"}
+	m := Mail{Addr: addr, From: "gitup@example.com", To: []string{"ops@example.com"}}
+	if err := m.Notify(n); err != nil {
+		t.Fatalf("Expected no error, received: %v", err)
+	}
+	tp := textproto.NewReader(bufio.NewReader(strings.NewReader(<-msg)))
+	h, err := tp.ReadMIMEHeader()
+	if err != nil || h.Get("To") != "ops@example.com" || h.Get("Subject") != "[gitup] app: v2.0.0 is available, currently on v1.0.0" {
+		t.Errorf("Expected email headers, received: %v, %v", h, err)
+	}
+	// Error on several lines.
+	addr, msg = fakeSMTP(t)
+	m.Addr = addr
+	n = Notification{Event: NotifyFailed, Repo: "app", From: "v1.0.0", To: "v2.0.0", Err: "exit status 1\r\nBcc: spy@example.com"}
+	if err = m.Notify(n); err != nil {
+		t.Fatalf("Expected no error, received: %v", err)
+	}
+	tp = textproto.NewReader(bufio.NewReader(strings.NewReader(<-msg)))
+	if h, err = tp.ReadMIMEHeader(); err != nil || h.Get("Bcc") != "" ||
+		h.Get("Subject") != "[gitup] app: update from v1.0.0 to v2.0.0 failed: exit status 1 Bcc: spy@example.com" {
+		t.Errorf("Expected the error in the subject, received: %v, %v", h, err)
+	}
+}
+
+// TestNotify_Timeout tests that the notifiers never wait for an unresponsive server.
+func TestNotify_Timeout(t *testing.T) {
+	timeout := NotifyTimeout
+	NotifyTimeout = 50 * time.Millisecond
+	defer func() { NotifyTimeout = timeout }()
+
+	done := make(chan struct{})
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-done
+	}))
+	defer srv.Close()
+	// Releases the handler before closing the server.
+	defer close(done)
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Unable to listen, received: %v", err)
+	}
+	defer l.Close()
+	go func() {
+		// Accepts the connections without ever greeting.
+		for {
+			conn, err := l.Accept()
+			if err != nil {
+				return
+			}
+			defer conn.Close()
+		}
+	}()
+	n := Notification{Event: NotifyApplied, Repo: "app", From: "v1.0.0", To: "v1.1.0"}
+	for name, nt := range map[string]Notifier{
+		"webhook": Webhook{URL: srv.URL},
+		"mail":    Mail{Addr: l.Addr().String(), From: "gitup@example.com", To: []string{"ops@example.com"}},
+	} {
+		start := time.Now()
+		if err := nt.Notify(n); err == nil || time.Since(start) > time.Second {
+			t.Errorf("Expected %v to time out, received: %v after %v", name, err, time.Since(start))
+		}
+	}
+}
+
+// TestCommand_Notify tests the command used as notifier.
+func TestCommand_Notify(t *testing.T) {
+	execCommand = fakeExecCommand
+
+	// Restore exec command behavior at the end of the test.
+	defer func() { execCommand = exec.Command }()
+
+	n := Notification{Event: NotifyFailed, Path: os.TempDir(), From: "v1.0.0", To: "v1.1.0", Err: errMsgFake}
+	if err := Command("notify-send gitup").Notify(n); err != nil {
+		t.Errorf("Expected no error, received: %v", err)
+	}
+	if err := Command("notify-send " + failScript).Notify(n); err == nil {
+		t.Error("Expected error with failing command")
+	}
+}
+
+// TestNotifyConfig_Notifier tests the notifiers built from the configuration.
+func TestNotifyConfig_Notifier(t *testing.T) {
+	for _, nc := range []NotifyConfig{
+		{Webhook: "http://localhost/hook"},
+		{Slack: "http://localhost/slack"},
+		{Command: "true"},
+		{Email: &EmailConfig{Addr: "localhost:25", Username: "gitup", To: []string{"ops@example.com"}}},
+	} {
+		if n, err := nc.Notifier(); err != nil || n == nil {
+			t.Errorf("Expected notifier for %#v, received: %v", nc, err)
+		}
+	}
+	if _, err := (NotifyConfig{Email: &EmailConfig{Addr: "localhost:25"}}).Notifier(); err == nil {
+		t.Error("Expected error without recipient")
+	}
+}
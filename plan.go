@@ -0,0 +1,201 @@
+package gitup
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/rvflash/gitup/internal/semver"
+)
+
+// Names used to describe a plan.
+var (
+	actionNames = [...]string{"noop", "manual", "auto"}
+	kindNames   = [...]string{"major", "minor", "patch", "pre-release"}
+)
+
+// PlanEntry describes the update that would be applied on a repository.
+type PlanEntry struct {
+	Path       string   `json:"path"`
+	Local      string   `json:"local"`
+	Latest     string   `json:"latest,omitempty"`
+	Target     string   `json:"target,omitempty"`
+	Action     string   `json:"action"`
+	Steps      []string `json:"steps,omitempty"`
+	Hooks      []string `json:"hooks,omitempty"`
+	Migrations []string `json:"migrations,omitempty"`
+	Blocked    string   `json:"blocked,omitempty"`
+	Err        string   `json:"error,omitempty"`
+}
+
+// Plan lists the updates that would be applied on a set of repositories.
+type Plan []PlanEntry
+
+// DryRun returns the plan of the updates that would be applied on these repositories with this strategy.
+func DryRun(s UpdateStrategy, repos ...*Repo) Plan {
+	p := make(Plan, len(repos))
+	for i, r := range repos {
+		p[i] = r.DryRun(s)
+	}
+	return p
+}
+
+// DryRun returns what the update would do with this strategy, without fetching the remote or checking anything out.
+// The latest version is the greatest tag already known by the local repository.
+func (r *Repo) DryRun(s UpdateStrategy) (e PlanEntry) {
+	// Works on a copy to keep unchanged the state of the repository.
+	d := *r
+	e.Path, e.Action = d.path, actionNames[Noop]
+	if d.remote == "" {
+		tags, err := d.versions().Tags()
+		if err != nil {
+			e.Err = err.Error()
+			return
+		}
+		if tags = semver.Sort(tags); len(tags) == 0 {
+			// Nothing known to move on: the remote is never fetched.
+			if e.Local, _, err = d.localVersion(); err != nil {
+				e.Err = err.Error()
+			}
+			return
+		}
+		d.remote = tags[len(tags)-1]
+	}
+	ok, err := d.check(s)
+	e.Local, e.Latest = d.local, d.remote
+	if err != nil {
+		e.Err = err.Error()
+		return
+	}
+	if !ok {
+		e.Blocked = d.blockedBy(s, d.local, d.remote)
+		return
+	}
+	e.Action = actionNames[d.upStrategy]
+	e.Steps = []string{d.remote}
+	if s.stepwise {
+		if e.Steps, err = d.stepPath(s); err != nil {
+			e.Err = err.Error()
+			return
+		}
+	}
+	if e.Target = e.Steps[len(e.Steps)-1]; e.Target != d.remote {
+		if s.isCheckpoint(e.Target) {
+			e.Blocked = "checkpoint on " + e.Target
+		} else {
+			e.Blocked = d.blockedBy(s, e.Target, d.remote)
+		}
+	}
+	if d.upStrategy == Auto && !s.inWindow(timeNow()) {
+		e.Blocked = "outside maintenance window"
+	}
+	e.Hooks = d.plannedHooks()
+	from := d.local
+	for _, tag := range e.Steps {
+		if d.migrationDir != "" {
+			list, err := d.migrations(from, tag)
+			if err != nil {
+				e.Err = err.Error()
+				return
+			}
+			e.Migrations = append(e.Migrations, list...)
+		}
+		from = tag
+	}
+	return
+}
+
+// blockedBy returns the reason preventing the move from the version to the tag, empty if none is known.
+func (r *Repo) blockedBy(s UpdateStrategy, from, tag string) string {
+	diff, err := semver.Compare(from, tag)
+	if err != nil {
+		return ""
+	}
+	kind := changeKind(diff)
+	if kind < MajorVersion {
+		return ""
+	}
+	if s.action(diff) == Noop {
+		return "noop strategy on " + kindNames[kind] + " version"
+	}
+	if action, err := r.eligible(s, diff, tag); err == nil && action == Noop {
+		return "staged rollout, host not yet selected for " + tag
+	}
+	return ""
+}
+
+// plannedHooks returns the description of the hooks run by an update.
+func (r *Repo) plannedHooks() (list []string) {
+	for _, point := range []uint8{BeforeCheck, BeforeUpdate} {
+		for _, h := range r.hooks[point] {
+			list = append(list, hookNames[point]+": "+describe(h))
+		}
+	}
+	for _, fn := range r.stepHooks {
+		list = append(list, "step: "+describe(fn))
+	}
+	for _, h := range r.hooks[AfterUpdate] {
+		list = append(list, hookNames[AfterUpdate]+": "+describe(h))
+	}
+	return
+}
+
+// WriteJSON writes the plan as JSON in w.
+func (p Plan) WriteJSON(w io.Writer) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(p)
+}
+
+// WriteText writes the plan as human readable text in w.
+func (p Plan) WriteText(w io.Writer) (err error) {
+	for _, e := range p {
+		if _, err = fmt.Fprintf(w, "%v: %v\n", e.Path, e.summary()); err != nil {
+			return
+		}
+		for _, l := range []struct {
+			name string
+			list []string
+		}{
+			{"steps", e.Steps},
+			{"hooks", e.Hooks},
+			{"migrations", e.Migrations},
+		} {
+			if len(l.list) == 0 {
+				continue
+			}
+			if _, err = fmt.Fprintf(w, "  %v: %v\n", l.name, strings.Join(l.list, ", ")); err != nil {
+				return
+			}
+		}
+		if e.Blocked != "" {
+			if _, err = fmt.Fprintf(w, "  blocked: %v\n", e.Blocked); err != nil {
+				return
+			}
+		}
+	}
+	return
+}
+
+// summary returns in one line the action planned.
+func (e PlanEntry) summary() string {
+	switch {
+	case e.Err != "":
+		return "error: " + e.Err
+	case e.Target == "":
+		if e.Blocked != "" {
+			return e.Local + " (" + e.Action + ", latest " + e.Latest + ")"
+		}
+		return e.Local + " (up to date)"
+	}
+	return e.Local + " -> " + e.Target + " (" + e.Action + ")"
+}
+
+// describe returns the name of a hook.
+func describe(h interface{}) string {
+	if s, ok := h.(fmt.Stringer); ok {
+		return s.String()
+	}
+	return fmt.Sprintf("%T", h)
+}
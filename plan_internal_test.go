@@ -0,0 +1,144 @@
+package gitup
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+var planTests = []struct {
+	git        *FakeStepFlow
+	strategy   [4]uint8
+	stepwise   bool
+	entry      PlanEntry // expected result
+	checkpoint string
+}{
+	{
+		&FakeStepFlow{FakeGitFlow: FakeGitFlow{localError: true}, tags: []string{"v1.0.0"}}, [4]uint8{Auto}, false,
+		PlanEntry{Latest: "v1.0.0", Action: "noop", Err: errMsgFake}, "",
+	},
+	{
+		&FakeStepFlow{FakeGitFlow: FakeGitFlow{localTag: "v1.1.0"}, tags: []string{"v1.0.0", "v1.1.0"}}, [4]uint8{Auto}, false,
+		PlanEntry{Local: "v1.1.0", Latest: "v1.1.0", Action: "noop"}, "",
+	},
+	{
+		&FakeStepFlow{FakeGitFlow: FakeGitFlow{localTag: "v1.0.0"}, tags: []string{"v1.0.0", "v2.0.0"}}, [4]uint8{Noop, Auto}, false,
+		PlanEntry{Local: "v1.0.0", Latest: "v2.0.0", Action: "noop", Blocked: "noop strategy on major version"}, "",
+	},
+	{
+		&FakeStepFlow{FakeGitFlow: FakeGitFlow{localTag: "v1.0.0"}, tags: []string{"v1.0.0", "v1.2.0", "v1.1.0"}}, [4]uint8{Manual}, false,
+		PlanEntry{
+			Local: "v1.0.0", Latest: "v1.2.0", Target: "v1.2.0", Action: "manual", Steps: []string{"v1.2.0"},
+			Hooks: []string{"before_update: make build", "step: gitup.StepFunc"},
+		}, "",
+	},
+	{
+		&FakeStepFlow{FakeGitFlow: FakeGitFlow{localTag: "v1.0.0"}, tags: []string{"v1.0.0", "v1.2.0", "v1.1.0", "v1.3.0"}}, [4]uint8{Auto}, true,
+		PlanEntry{
+			Local: "v1.0.0", Latest: "v1.3.0", Target: "v1.2.0", Action: "auto", Steps: []string{"v1.1.0", "v1.2.0"},
+			Hooks: []string{"before_update: make build", "step: gitup.StepFunc"}, Blocked: "checkpoint on v1.2.0",
+		}, "v1.2.0",
+	},
+	{
+		&FakeStepFlow{FakeGitFlow: FakeGitFlow{localTag: "v1.0.0"}, tags: []string{"v1.0.0", "v1.1.0", "v2.0.0"}}, [4]uint8{Noop, Auto}, true,
+		PlanEntry{
+			Local: "v1.0.0", Latest: "v2.0.0", Target: "v1.1.0", Action: "auto", Steps: []string{"v1.1.0"},
+			Hooks: []string{"before_update: make build", "step: gitup.StepFunc"}, Blocked: "noop strategy on major version",
+		}, "",
+	},
+	{
+		// Without any tag known, the remote is never fetched.
+		&FakeStepFlow{FakeGitFlow: FakeGitFlow{localTag: "v1.0.0", remoteError: true}}, [4]uint8{Auto}, false,
+		PlanEntry{Local: "v1.0.0", Action: "noop"}, "",
+	},
+}
+
+// TestRepo_DryRun tests DryRun method with various repositories and strategies.
+func TestRepo_DryRun(t *testing.T) {
+	for _, pt := range planTests {
+		s := UpdateStrategy{until: pt.strategy}
+		s.SetStepwise(pt.stepwise)
+		if pt.checkpoint != "" {
+			s.AddCheckpoint(pt.checkpoint)
+		}
+		r := &Repo{git: pt.git}
+		r.AddHook(BeforeUpdate, Command("make build"))
+		r.AddStepHook(func(from, to string) error { return nil })
+
+		e := r.DryRun(s)
+		exp, _ := json.Marshal(pt.entry)
+		if got, _ := json.Marshal(e); string(got) != string(exp) {
+			t.Errorf("Expected plan %s, received: %s", exp, got)
+		}
+		if len(pt.git.checkouts) > 0 || r.local != "" || r.remote != "" {
+			t.Errorf("Expected no side effect on the repository, received: %#v", r)
+		}
+	}
+}
+
+// TestRepo_DryRun_Rollout tests the plan of a repository whose host is not yet selected by the staged rollout.
+func TestRepo_DryRun_Rollout(t *testing.T) {
+	ro, _ := NewRollout("host")
+	if err := ro.AddStep(MinorVersion, 0, 0); err != nil {
+		t.Fatalf("Expected no error, received: %v", err)
+	}
+	s := UpdateStrategy{until: [4]uint8{Auto}}
+	s.SetRollout(ro)
+	r := &Repo{git: &FakeStepFlow{FakeGitFlow: FakeGitFlow{localTag: "v1.0.0"}, tags: []string{"v1.0.0", "v1.1.0"}}}
+	if e := r.DryRun(s); e.Blocked != "staged rollout, host not yet selected for v1.1.0" || e.Target != "" {
+		t.Errorf("Expected an update blocked by the staged rollout, received: %+v", e)
+	}
+	// Without eligible version, nothing blocks the repository.
+	s = UpdateStrategy{until: [4]uint8{Auto}}
+	r = &Repo{git: &FakeStepFlow{FakeGitFlow: FakeGitFlow{localTag: "v1.1.0"}, tags: []string{"v1.0.0", "v1.1.0"}}}
+	if e := r.DryRun(s); e.Blocked != "" || e.Action != "noop" {
+		t.Errorf("Expected nothing blocked, received: %+v", e)
+	}
+}
+
+// TestPlan_Write tests the rendering of a plan as text or JSON.
+func TestPlan_Write(t *testing.T) {
+	p := Plan{
+		{Path: "/srv/app", Local: "v1.0.0", Latest: "v1.3.0", Target: "v1.2.0", Action: "auto",
+			Steps: []string{"v1.1.0", "v1.2.0"}, Hooks: []string{"after_update: make"}, Blocked: "checkpoint on v1.2.0"},
+		{Path: "/srv/api", Local: "v2.0.0", Latest: "v2.0.0", Action: "noop"},
+		{Path: "/srv/web", Err: errMsgFake},
+	}
+	buf := new(bytes.Buffer)
+	if err := p.WriteText(buf); err != nil {
+		t.Fatalf("Expected no error, received: %v", err)
+	}
+	exp := strings.Join([]string{
+		"/srv/app: v1.0.0 -> v1.2.0 (auto)",
+		"  steps: v1.1.0, v1.2.0",
+		"  hooks: after_update: make",
+		"  blocked: checkpoint on v1.2.0",
+		"/srv/api: v2.0.0 (up to date)",
+		"/srv/web: error: " + errMsgFake,
+		"",
+	}, "\n")
+	if buf.String() != exp {
+		t.Errorf("Expected text %q, received: %q", exp, buf.String())
+	}
+	buf.Reset()
+	if err := p.WriteJSON(buf); err != nil {
+		t.Fatalf("Expected no error, received: %v", err)
+	}
+	var res Plan
+	if err := json.Unmarshal(buf.Bytes(), &res); err != nil || len(res) != len(p) || res[0].Target != "v1.2.0" {
+		t.Errorf("Expected valid JSON plan, received: %s (%v)", buf.String(), err)
+	}
+}
+
+// TestDryRun tests the plan of a set of repositories.
+func TestDryRun(t *testing.T) {
+	p := DryRun(
+		UpdateStrategy{until: [4]uint8{Auto}},
+		&Repo{git: &FakeStepFlow{FakeGitFlow: FakeGitFlow{localTag: "v1.0.0"}, tags: []string{"v1.0.1"}}, path: "/a"},
+		&Repo{git: &FakeStepFlow{FakeGitFlow: FakeGitFlow{localTag: "v1.0.1"}, tags: []string{"v1.0.1"}}, path: "/b"},
+	)
+	if len(p) != 2 || p[0].Path != "/a" || p[0].Target != "v1.0.1" || p[1].Target != "" {
+		t.Errorf("Expected a plan by repository, received: %#v", p)
+	}
+}
@@ -0,0 +1,93 @@
+package gitup
+
+import (
+	"errors"
+	"hash/fnv"
+	"os"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Error messages.
+const errMsgPercent = "percentage must be between 0 and 100"
+
+// RolloutStep opens an update to a percentage of the hosts once the version is old enough.
+type RolloutStep struct {
+	After   time.Duration
+	Percent uint8
+}
+
+// Rollout is a policy over the update strategy to stage the updates across a fleet of hosts.
+// By type of version, it defines the percentage of hosts to update according to the age of the version.
+// Each host decides deterministically with a hash of its identifier and the version.
+type Rollout struct {
+	hostID string
+	steps  [4][]RolloutStep
+}
+
+// Enable testing by mocking os.Hostname.
+var hostname = os.Hostname
+
+// NewRollout returns a staged rollout for this host, by default identified by its hostname.
+func NewRollout(hostID string) (*Rollout, error) {
+	if hostID = strings.TrimSpace(hostID); hostID == "" {
+		var err error
+		if hostID, err = hostname(); err != nil {
+			return nil, err
+		}
+	}
+	return &Rollout{hostID: hostID}, nil
+}
+
+// AddStep opens the updates of this type of version to a percentage of the hosts,
+// once the version has been tagged for the given duration.
+// Without any step, all the hosts are updated as soon as the version is tagged.
+func (r *Rollout) AddStep(version uint8, after time.Duration, percent uint8) error {
+	if version >= BuildMetadata {
+		return errors.New(errMsgVersion)
+	}
+	if percent > 100 {
+		return errors.New(errMsgPercent)
+	}
+	r.steps[version] = append(r.steps[version], RolloutStep{After: after, Percent: percent})
+	sort.Sort(byAge(r.steps[version]))
+	return nil
+}
+
+// Allows returns true if this host has to be updated to the tag of this type of version, with this age.
+func (r *Rollout) Allows(version int8, tag string, age time.Duration) bool {
+	return r.bucket(tag) < r.percent(version, age)
+}
+
+// percent returns the percentage of hosts to update for the type of version with this age.
+func (r *Rollout) percent(version int8, age time.Duration) (percent uint8) {
+	if version < MajorVersion || version > PreReleaseVersion || len(r.steps[version]) == 0 {
+		return 100
+	}
+	for _, s := range r.steps[version] {
+		if s.After <= age && s.Percent > percent {
+			percent = s.Percent
+		}
+	}
+	return
+}
+
+// bucket returns the bucket of the host for this tag, between 0 and 99.
+func (r *Rollout) bucket(tag string) uint8 {
+	h := fnv.New32a()
+	h.Write([]byte(r.hostID + "@" + tag))
+	return uint8(h.Sum32() % 100)
+}
+
+// byAge implements sort.Interface to order rollout steps by age.
+type byAge []RolloutStep
+
+func (p byAge) Len() int           { return len(p) }
+func (p byAge) Less(i, j int) bool { return p[i].After < p[j].After }
+func (p byAge) Swap(i, j int)      { p[i], p[j] = p[j], p[i] }
+
+// SetRollout defines the staged rollout policy to apply over the strategy.
+func (s *UpdateStrategy) SetRollout(r *Rollout) {
+	s.rollout = r
+}
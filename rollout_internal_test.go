@@ -0,0 +1,113 @@
+package gitup
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"testing"
+	"time"
+)
+
+var percentTests = []struct {
+	version int8          // input
+	age     time.Duration // input
+	percent uint8         // expected result
+}{
+	{MajorVersion, 0, 100},
+	{MinorVersion, -time.Hour, 0},
+	{MinorVersion, 0, 10},
+	{MinorVersion, 47 * time.Hour, 10},
+	{MinorVersion, 48 * time.Hour, 100},
+	{PatchVersion, 0, 100},
+	{-1, 0, 100},
+}
+
+// TestNewRollout tests NewRollout method with or without host identifier.
+func TestNewRollout(t *testing.T) {
+	// Restore hostname behavior at the end of the test.
+	defer func() { hostname = os.Hostname }()
+
+	hostname = func() (string, error) { return "", errors.New(errMsgFake) }
+	if _, err := NewRollout(" "); err == nil {
+		t.Error("Expected error without hostname")
+	}
+	hostname = func() (string, error) { return "web-1", nil }
+	if r, err := NewRollout(""); err != nil || r.hostID != "web-1" {
+		t.Errorf("Expected hostname as host identifier, received: %v", err)
+	}
+	if r, _ := NewRollout("web-2"); r.hostID != "web-2" {
+		t.Errorf("Expected host identifier, received: %v", r.hostID)
+	}
+}
+
+// TestRollout_AddStep tests AddStep method and the percentage by age.
+func TestRollout_AddStep(t *testing.T) {
+	r, _ := NewRollout("web-1")
+	if err := r.AddStep(BuildMetadata, 0, 10); err == nil {
+		t.Error("Expected error with unknown version")
+	}
+	if err := r.AddStep(MinorVersion, 0, 101); err == nil {
+		t.Error("Expected error with invalid percentage")
+	}
+	r.AddStep(MinorVersion, 48*time.Hour, 100)
+	r.AddStep(MinorVersion, 0, 10)
+	for _, pt := range percentTests {
+		if p := r.percent(pt.version, pt.age); p != pt.percent {
+			t.Errorf("Expected %d%% for version %v with age %v, received: %d%%", pt.percent, pt.version, pt.age, p)
+		}
+	}
+}
+
+// TestRollout_Allows tests that the hosts are deterministically distributed.
+func TestRollout_Allows(t *testing.T) {
+	var selected int
+	for i := 0; i < 1000; i++ {
+		r, _ := NewRollout(fmt.Sprintf("web-%d", i))
+		r.AddStep(MinorVersion, 0, 10)
+		ok := r.Allows(MinorVersion, "v1.1.0", time.Hour)
+		if ok != r.Allows(MinorVersion, "v1.1.0", time.Hour) {
+			t.Fatal("Expected deterministic decision")
+		}
+		if ok {
+			selected++
+		}
+	}
+	if selected < 50 || selected > 150 {
+		t.Errorf("Expected around 10%% of the hosts, received: %d on 1000", selected)
+	}
+}
+
+// TestRepo_InDemand_Rollout tests that InDemand considers the age of the tag and the bucket of the host.
+func TestRepo_InDemand_Rollout(t *testing.T) {
+	// Restore the current time at the end of the test.
+	defer func() { timeNow = time.Now }()
+	timeNow = func() time.Time { return date(3, 10, 0) }
+
+	// Looks for a host out of the first 10%.
+	var ro *Rollout
+	for i := 0; ro == nil; i++ {
+		if r, _ := NewRollout(fmt.Sprintf("web-%d", i)); r.bucket("v1.1.0") >= 10 {
+			ro = r
+		}
+	}
+	ro.AddStep(MinorVersion, 0, 10)
+	ro.AddStep(MinorVersion, 48*time.Hour, 100)
+	s := UpdateStrategy{until: [4]uint8{Auto}}
+	s.SetRollout(ro)
+
+	git := &FakeStepFlow{
+		FakeGitFlow: FakeGitFlow{localTag: "v1.0.0", remoteTag: "v1.1.0"},
+		dates:       map[string]time.Time{"v1.1.0": date(2, 10, 0)},
+	}
+	r := &Repo{git: git}
+	if r.InDemand(s) {
+		t.Error("Expected no update for a host out of the first 10%")
+	}
+	if e := r.DryRun(s); e.Blocked != "staged rollout, host not yet selected for v1.1.0" {
+		t.Errorf("Expected blocking policy, received: %#v", e)
+	}
+	git.dates["v1.1.0"] = date(1, 10, 0)
+	if !r.InDemand(s) {
+		t.Error("Expected update for all hosts after 48 hours")
+	}
+}
@@ -0,0 +1,35 @@
+package server
+
+import (
+	"net/http"
+
+	"github.com/rvflash/gitup"
+)
+
+// metricsContentType is the content type of the Prometheus text format.
+const metricsContentType = "text/plain; version=0.0.4; charset=utf-8"
+
+// metricsHandler serves the metrics of the repositories.
+type metricsHandler struct {
+	manager func() *gitup.Manager
+}
+
+// Metrics returns a handler serving on GET the metrics of the repositories of the current manager,
+// in the Prometheus text format. Unlike the API, it is not authenticated.
+func Metrics(manager func() *gitup.Manager) http.Handler {
+	return metricsHandler{manager: manager}
+}
+
+// ServeHTTP implements the http.Handler interface.
+func (h metricsHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if !allow(w, r, http.MethodGet) {
+		return
+	}
+	m := h.manager()
+	if m == nil {
+		writeError(w, http.StatusServiceUnavailable, errMsgUnavailable)
+		return
+	}
+	w.Header().Set("Content-Type", metricsContentType)
+	m.WriteMetrics(w)
+}
@@ -0,0 +1,184 @@
+// Package server provides an HTTP API to get the status of the repositories
+// and to trigger their checks, updates and rollbacks.
+//
+//	GET  /repos                  lists the status of all the repositories
+//	GET  /repos/{name}           returns the status of the repository
+//	POST /repos/{name}/check     fetches the repository and returns if an update is available
+//	POST /repos/{name}/update    updates the repository, without demanding confirmation
+//	POST /repos/{name}/rollback  moves back the repository on its previous version
+//
+// With a token, each request must be authenticated with the header "Authorization: Bearer <token>".
+// Without token, the API is read-only: the checks, updates and rollbacks are forbidden.
+package server
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"net"
+	"net/http"
+	"strings"
+
+	"github.com/rvflash/gitup"
+)
+
+// Error messages.
+const (
+	errMsgUnauthorized = "unauthorized"
+	errMsgReadOnly     = "read-only without token"
+	errMsgNotFound     = "not found"
+	errMsgMethod       = "method not allowed"
+	errMsgUnavailable  = "no repository available"
+)
+
+const reposPath = "/repos"
+
+// Server serves the HTTP API.
+type Server struct {
+	manager func() *gitup.Manager
+	token   string
+}
+
+// CheckResult is the response of a check.
+type CheckResult struct {
+	Available bool         `json:"available"`
+	Status    gitup.Status `json:"status"`
+}
+
+// errorResult is the response on error.
+type errorResult struct {
+	Error string `json:"error"`
+}
+
+// New returns a server of the repositories handled by the manager returned by the function,
+// like gitup.Watcher.Manager. Without token, the requests are not authenticated and the API is read-only.
+func New(manager func() *gitup.Manager, token string) *Server {
+	return &Server{manager: manager, token: token}
+}
+
+// ServeHTTP implements the http.Handler interface.
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if !s.authorized(r) {
+		w.Header().Set("WWW-Authenticate", "Bearer")
+		writeError(w, http.StatusUnauthorized, errMsgUnauthorized)
+		return
+	}
+	m := s.manager()
+	if m == nil {
+		writeError(w, http.StatusServiceUnavailable, errMsgUnavailable)
+		return
+	}
+	if r.URL.Path != reposPath && !strings.HasPrefix(r.URL.Path, reposPath+"/") {
+		writeError(w, http.StatusNotFound, errMsgNotFound)
+		return
+	}
+	path := strings.Trim(strings.TrimPrefix(r.URL.Path, reposPath), "/")
+	parts := strings.Split(path, "/")
+	switch {
+	case path == "":
+		if allow(w, r, http.MethodGet) {
+			writeJSON(w, http.StatusOK, m.Statuses())
+		}
+	case len(parts) == 1:
+		if allow(w, r, http.MethodGet) {
+			st, err := m.Status(parts[0])
+			writeResult(w, st, err)
+		}
+	case len(parts) == 2:
+		s.action(w, r, m, parts[0], parts[1])
+	default:
+		writeError(w, http.StatusNotFound, errMsgNotFound)
+	}
+}
+
+// action triggers the action on the named repository.
+func (s *Server) action(w http.ResponseWriter, r *http.Request, m *gitup.Manager, name, action string) {
+	if s.token == "" {
+		// Nobody can be trusted to act on the repositories.
+		writeError(w, http.StatusForbidden, errMsgReadOnly)
+		return
+	}
+	var err error
+	switch action {
+	case "check":
+		if !allow(w, r, http.MethodPost) {
+			return
+		}
+		var res CheckResult
+		res.Available, err = m.Check(name)
+		res.Status, _ = m.Status(name)
+		writeResult(w, res, err)
+		return
+	case "update":
+		if !allow(w, r, http.MethodPost) {
+			return
+		}
+		err = m.ForceUpdateAs(name, actor(r))
+	case "rollback":
+		if !allow(w, r, http.MethodPost) {
+			return
+		}
+		err = m.RollbackAs(name, actor(r))
+	default:
+		writeError(w, http.StatusNotFound, errMsgNotFound)
+		return
+	}
+	st, _ := m.Status(name)
+	writeResult(w, st, err)
+}
+
+// authorized returns true if the request is authenticated with the token.
+func (s *Server) authorized(r *http.Request) bool {
+	if s.token == "" {
+		return true
+	}
+	auth := r.Header.Get("Authorization")
+	if !strings.HasPrefix(auth, "Bearer ") {
+		return false
+	}
+	return subtle.ConstantTimeCompare([]byte(strings.TrimPrefix(auth, "Bearer ")), []byte(s.token)) == 1
+}
+
+// actor returns the identity of the caller recorded in the audit log: its address.
+func actor(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		host = r.RemoteAddr
+	}
+	return "api:" + host
+}
+
+// allow returns true if the request uses the method, otherwise it responds with an error.
+func allow(w http.ResponseWriter, r *http.Request, method string) bool {
+	if r.Method == method {
+		return true
+	}
+	w.Header().Set("Allow", method)
+	writeError(w, http.StatusMethodNotAllowed, errMsgMethod)
+	return false
+}
+
+// writeResult writes the value as JSON, or the error with the matching status code.
+func writeResult(w http.ResponseWriter, v interface{}, err error) {
+	switch err {
+	case nil:
+		writeJSON(w, http.StatusOK, v)
+	case gitup.ErrUnknownRepo:
+		writeError(w, http.StatusNotFound, err.Error())
+	case gitup.ErrNoUpdate, gitup.ErrNoRollback:
+		writeError(w, http.StatusConflict, err.Error())
+	default:
+		writeError(w, http.StatusInternalServerError, err.Error())
+	}
+}
+
+// writeError writes the error message as JSON.
+func writeError(w http.ResponseWriter, code int, msg string) {
+	writeJSON(w, code, errorResult{Error: msg})
+}
+
+// writeJSON writes the value as JSON with the status code.
+func writeJSON(w http.ResponseWriter, code int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	json.NewEncoder(w).Encode(v)
+}
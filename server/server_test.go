@@ -0,0 +1,148 @@
+package server_test
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/rvflash/gitup"
+	"github.com/rvflash/gitup/server"
+)
+
+const tokenTest = "s3cr3t"
+
+var serverTests = []struct {
+	method, path, token string // input
+	code                int    // expected result
+	body                string
+}{
+	{"GET", "/repos", "", http.StatusUnauthorized, `"error":"unauthorized"`},
+	{"GET", "/repos", "wrong", http.StatusUnauthorized, `"error":"unauthorized"`},
+	{"GET", "/repos", tokenTest, http.StatusOK, `"name":"app","path":`},
+	{"GET", "/reposx", tokenTest, http.StatusNotFound, `"error":"not found"`},
+	{"POST", "/repos", tokenTest, http.StatusMethodNotAllowed, `"error":"method not allowed"`},
+	{"GET", "/repos/app", tokenTest, http.StatusOK, `"name":"app"`},
+	{"GET", "/repos/api", tokenTest, http.StatusNotFound, `"error":"unknown repository"`},
+	{"GET", "/repos/app/check", tokenTest, http.StatusMethodNotAllowed, `"error":"method not allowed"`},
+	{"POST", "/repos/app/rollback", tokenTest, http.StatusConflict, `"error":"no update to roll back"`},
+	{"POST", "/repos/app/check", tokenTest, http.StatusOK, `{"available":true,"status":{"name":"app"`},
+	{"POST", "/repos/app/update", tokenTest, http.StatusOK, `"local":"v1.1.0"`},
+	{"POST", "/repos/app/update", tokenTest, http.StatusConflict, `"error":"no available update"`},
+	{"POST", "/repos/app/rollback", tokenTest, http.StatusOK, `"local":"v1.0.0"`},
+	{"POST", "/repos/app/deploy", tokenTest, http.StatusNotFound, `"error":"not found"`},
+	{"POST", "/repos/app/check/now", tokenTest, http.StatusNotFound, `"error":"not found"`},
+}
+
+// git runs the git command in the directory, with the environment variables.
+func git(t *testing.T, dir string, env []string, args ...string) {
+	args = append([]string{"-C", dir, "-c", "user.name=gitup", "-c", "user.email=gitup@example.com"}, args...)
+	cmd := exec.Command("git", args...)
+	cmd.Env = append(os.Environ(), env...)
+	if out, err := cmd.CombinedOutput(); err != nil {
+		t.Fatalf("Unable to run git %v, received: %v, %s", args, err, out)
+	}
+}
+
+// newGitRepo returns a Git repository on the tag v1.0.0, cloned from a remote with the tag v1.1.0.
+func newGitRepo(t *testing.T) (dir string) {
+	var err error
+	if dir, err = ioutil.TempDir(os.TempDir(), "gitup"); err != nil {
+		t.Fatalf("Unable to create directory, received: %v", err)
+	}
+	remote, local := filepath.Join(dir, "remote"), filepath.Join(dir, "app")
+	os.Mkdir(remote, 0755)
+	git(t, remote, nil, "init", "-q")
+	for i, tag := range []string{"v1.0.0", "v1.1.0"} {
+		// Commits on distinct dates to order them.
+		date := []string{"GIT_COMMITTER_DATE=2017-06-0" + strconv.Itoa(i+1) + "T10:00:00Z"}
+		git(t, remote, date, "commit", "-q", "--allow-empty", "-m", tag)
+		git(t, remote, nil, "tag", tag)
+	}
+	git(t, dir, nil, "clone", "-q", remote, local)
+	git(t, local, nil, "checkout", "-q", "tags/v1.0.0")
+	return
+}
+
+// TestServer_ServeHTTP tests the HTTP API on a real Git repository.
+func TestServer_ServeHTTP(t *testing.T) {
+	dir := newGitRepo(t)
+	defer os.RemoveAll(dir)
+
+	r, err := gitup.NewRepo(filepath.Join(dir, "app"))
+	if err != nil {
+		t.Fatalf("Expected no error, received: %v", err)
+	}
+	s := gitup.UpdateStrategy{}
+	s.AddStrategy(gitup.MinorVersion, gitup.Manual)
+	m := gitup.NewManager()
+	m.Add("app", r, s)
+
+	h := server.New(func() *gitup.Manager { return m }, tokenTest)
+	for _, st := range serverTests {
+		req := httptest.NewRequest(st.method, st.path, nil)
+		if st.token != "" {
+			req.Header.Set("Authorization", "Bearer "+st.token)
+		}
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, req)
+		if w.Code != st.code || !strings.Contains(w.Body.String(), st.body) {
+			t.Errorf("Expected %d with %v for %v %v, received: %d, %v", st.code, st.body, st.method, st.path, w.Code, w.Body.String())
+		}
+		if ct := w.Header().Get("Content-Type"); ct != "application/json" {
+			t.Errorf("Expected JSON response for %v %v, received: %v", st.method, st.path, ct)
+		}
+	}
+}
+
+// TestServer_ServeHTTP_Unavailable tests the API without manager and without token.
+func TestServer_ServeHTTP_Unavailable(t *testing.T) {
+	h := server.New(func() *gitup.Manager { return nil }, "")
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest("GET", "/repos", nil))
+	if w.Code != http.StatusServiceUnavailable {
+		t.Errorf("Expected unavailable service, received: %d", w.Code)
+	}
+	h = server.New(func() *gitup.Manager { return gitup.NewManager() }, "")
+	w = httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest("GET", "/repos", nil))
+	var res gitup.Statuses
+	if err := json.Unmarshal(w.Body.Bytes(), &res); w.Code != http.StatusOK || err != nil || len(res) != 0 {
+		t.Errorf("Expected empty list, received: %d, %v", w.Code, w.Body.String())
+	}
+	// Without token, the API is read-only.
+	for _, action := range []string{"check", "update", "rollback"} {
+		w = httptest.NewRecorder()
+		h.ServeHTTP(w, httptest.NewRequest("POST", "/repos/app/"+action, nil))
+		if w.Code != http.StatusForbidden || !strings.Contains(w.Body.String(), `"error":"read-only without token"`) {
+			t.Errorf("Expected forbidden %v without token, received: %d, %v", action, w.Code, w.Body.String())
+		}
+	}
+}
+
+// TestMetrics tests that the metrics are served in the Prometheus text format.
+func TestMetrics(t *testing.T) {
+	h := server.Metrics(func() *gitup.Manager { return nil })
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest("GET", "/metrics", nil))
+	if w.Code != http.StatusServiceUnavailable {
+		t.Errorf("Expected unavailable service, received: %d", w.Code)
+	}
+	h = server.Metrics(func() *gitup.Manager { return gitup.NewManager() })
+	w = httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest("POST", "/metrics", nil))
+	if w.Code != http.StatusMethodNotAllowed {
+		t.Errorf("Expected method not allowed, received: %d", w.Code)
+	}
+	w = httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest("GET", "/metrics", nil))
+	if w.Code != http.StatusOK || !strings.Contains(w.Body.String(), "# TYPE gitup_checks_total counter") {
+		t.Errorf("Expected metrics, received: %d, %v", w.Code, w.Body.String())
+	}
+}
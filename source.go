@@ -0,0 +1,139 @@
+package gitup
+
+import (
+	"errors"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/rvflash/gitup/internal/gitflow"
+)
+
+const (
+	errMsgSource    = "source or installer is undefined"
+	errMsgTagLister = "source does not list the metadata of its tags"
+)
+
+// Source provides the available versions and their metadata.
+type Source interface {
+	// LastTag returns the latest version, after fetching the new ones if necessary.
+	LastTag() (string, error)
+	// Tags returns the list of the known versions.
+	Tags() ([]string, error)
+	// TagDate returns the publication date of the version.
+	TagDate(string) (time.Time, error)
+}
+
+// Installer switches to a version.
+type Installer interface {
+	// LocalTag returns the installed version.
+	LocalTag() (string, error)
+	// CheckoutTag installs the version.
+	CheckoutTag(string) error
+}
+
+// Tag is a tag with its metadata: target object and commit, date, signature presence and subject.
+type Tag = gitflow.Tag
+
+// TagLister is implemented by the sources listing their tags with their metadata at once, like a Git repository.
+type TagLister interface {
+	ListTags() ([]Tag, error)
+}
+
+// LocalState is the position of HEAD relative to the tags: its tags, the nearest one and the commits after it.
+type LocalState = gitflow.LocalState
+
+// LocalStater is implemented by the installers knowing whether the local version has commits after its tag,
+// like a Git repository.
+type LocalStater interface {
+	LocalState() (LocalState, error)
+}
+
+// ReleaseNotes is implemented by the sources providing the notes of a version.
+// They are displayed before demanding the confirmation of a manual update.
+type ReleaseNotes interface {
+	Notes(tag string) (string, error)
+}
+
+// workDirer is implemented by the installers whose installed version is outside the repository's root.
+type workDirer interface {
+	WorkDir() string
+}
+
+// tagFetcher is implemented by the Git repositories, to get a tag listed by another source.
+type tagFetcher interface {
+	FetchTag(tag string) error
+}
+
+// NewRepoFrom returns a repository whose versions come from the source and are installed by the installer.
+// The path is the working directory of the migrations and the hooks, it is not required to be a Git repository.
+// Without Git repository, its status has neither remote nor local modifications.
+func NewRepoFrom(path string, s Source, i Installer) (*Repo, error) {
+	if s == nil || i == nil {
+		return nil, errors.New(errMsgSource)
+	}
+	return &Repo{path: strings.TrimSpace(path), source: s, installer: i}, nil
+}
+
+// SetSource defines the source of the versions, instead of the tags of the Git repository.
+func (r *Repo) SetSource(s Source) {
+	r.source = s
+}
+
+// SetInstaller defines how to install a version, instead of the checkout of a Git tag.
+func (r *Repo) SetInstaller(i Installer) {
+	r.installer = i
+}
+
+// ListTags returns the known versions with their metadata, if the source lists them.
+func (r *Repo) ListTags() ([]Tag, error) {
+	if l, ok := r.versions().(TagLister); ok {
+		return l.ListTags()
+	}
+	return nil, errors.New(errMsgTagLister)
+}
+
+// localVersion returns the installed version with the number of local commits after it.
+func (r *Repo) localVersion() (string, int, error) {
+	ls, ok := r.installs().(LocalStater)
+	if !ok {
+		tag, err := r.installs().LocalTag()
+		return tag, 0, err
+	}
+	s, err := ls.LocalState()
+	if err != nil {
+		return "", 0, err
+	}
+	if s.Nearest == "" {
+		// Same error as without state.
+		_, err = r.installs().LocalTag()
+	}
+	return s.Nearest, s.Distance, err
+}
+
+// versions returns the source of the versions: the one defined or by default, the Git repository.
+func (r *Repo) versions() Source {
+	if r.source != nil {
+		return r.source
+	}
+	return r.git
+}
+
+// installs returns the installer of the versions: the one defined or by default, the Git repository.
+func (r *Repo) installs() Installer {
+	if r.installer != nil {
+		return r.installer
+	}
+	return r.git
+}
+
+// workDir returns the directory of the installed version, where the migrations and the hooks run.
+// Until a version is installed there, it is the repository's root.
+func (r *Repo) workDir() string {
+	if w, ok := r.installs().(workDirer); ok {
+		if _, err := os.Stat(w.WorkDir()); err == nil {
+			return w.WorkDir()
+		}
+	}
+	return r.path
+}
@@ -0,0 +1,98 @@
+package gitup
+
+import (
+	"os"
+	"testing"
+	"time"
+)
+
+// FakeArtifacts is an in-memory Source and Installer of versions.
+type FakeArtifacts struct {
+	versions  []string
+	installed string
+}
+
+// LastTag implements the Source interface.
+func (a *FakeArtifacts) LastTag() (string, error) {
+	return a.versions[len(a.versions)-1], nil
+}
+
+// Tags implements the Source interface.
+func (a *FakeArtifacts) Tags() ([]string, error) {
+	return a.versions, nil
+}
+
+// TagDate implements the Source interface.
+func (a *FakeArtifacts) TagDate(string) (time.Time, error) {
+	return time.Time{}, nil
+}
+
+// LocalTag implements the Installer interface.
+func (a *FakeArtifacts) LocalTag() (string, error) {
+	return a.installed, nil
+}
+
+// CheckoutTag implements the Installer interface.
+func (a *FakeArtifacts) CheckoutTag(tag string) error {
+	a.installed = tag
+	return nil
+}
+
+// TestNewRepoFrom tests an update without Git repository.
+func TestNewRepoFrom(t *testing.T) {
+	if _, err := NewRepoFrom("/srv/app", nil, nil); err == nil {
+		t.Error("Expected error without source nor installer")
+	}
+	a := &FakeArtifacts{versions: []string{"v1.0.0", "v1.0.1", "v1.1.0"}, installed: "v1.0.0"}
+	r, err := NewRepoFrom("/srv/app", a, a)
+	if err != nil {
+		t.Fatalf("Expected no error, received: %v", err)
+	}
+	s := UpdateStrategy{until: [4]uint8{Noop, Noop, Auto}, stepwise: true}
+	if err = r.Update(s); err != nil || a.installed != "v1.0.1" {
+		t.Errorf("Expected update on v1.0.1, received: %v, %v", a.installed, err)
+	}
+	if st := r.Status(); st.Local != "v1.0.1" || st.Remote != "" || st.LastError != "" {
+		t.Errorf("Expected status without Git, received: %#v", st)
+	}
+}
+
+// TestRepo_SetSource tests the versions of a source installed by the Git repository.
+func TestRepo_SetSource(t *testing.T) {
+	git := &FakeStepFlow{FakeGitFlow: FakeGitFlow{localTag: "v1.0.0", remoteTag: "v1.0.0"}}
+	r := &Repo{git: git}
+	r.SetSource(&FakeArtifacts{versions: []string{"v1.0.0", "v1.2.0"}})
+	if err := r.Update(UpdateStrategy{until: [4]uint8{Auto}}); err != nil || len(git.checkouts) != 1 || git.checkouts[0] != "v1.2.0" {
+		t.Errorf("Expected checkout of v1.2.0, received: %v, %v", git.checkouts, err)
+	}
+	a := &FakeArtifacts{versions: []string{"v1.2.0"}, installed: "v1.2.0"}
+	r.SetInstaller(a)
+	r.refresh()
+	if r.InDemand(UpdateStrategy{until: [4]uint8{Auto}}) {
+		t.Error("Expected no update with the version of the installer")
+	}
+}
+
+// TestRepo_ListTags tests the tags with their metadata, only listed by some sources.
+func TestRepo_ListTags(t *testing.T) {
+	remote := newRemote(t, "v1.0.0", "v1.1.0")
+	defer os.RemoveAll(remote)
+
+	r, err := NewRepo(remote)
+	if err != nil {
+		t.Fatalf("Expected no error, received: %v", err)
+	}
+	tags, err := r.ListTags()
+	if err != nil {
+		t.Fatalf("Expected no error, received: %v", err)
+	}
+	if len(tags) != 2 || tags[1].Name != "v1.1.0" || tags[1].Commit == "" || tags[1].Subject != "v1.1.0" || tags[1].Signed {
+		t.Errorf("Expected the tags v1.0.0 and v1.1.0 with their metadata, received: %+v", tags)
+	}
+	if r, err = NewRepoFrom("", FakeGitFlow{}, FakeGitFlow{}); err != nil {
+		t.Fatalf("Expected no error, received: %v", err)
+	}
+	if _, err = r.ListTags(); err == nil || err.Error() != errMsgTagLister {
+		t.Errorf("Expected error %q, received: %v", errMsgTagLister, err)
+	}
+}
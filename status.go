@@ -0,0 +1,106 @@
+package gitup
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/rvflash/gitup/internal/gitflow"
+	"github.com/rvflash/gitup/internal/semver"
+)
+
+// Status represents the state of a repository, as known since its last check.
+type Status struct {
+	Name         string              `json:"name,omitempty"`
+	Path         string              `json:"path"`
+	Remote       string              `json:"remote,omitempty"`
+	Local        string              `json:"local"`
+	Ahead        int                 `json:"ahead,omitempty"`
+	Latest       string              `json:"latest,omitempty"`
+	Relationship semver.Relationship `json:"relationship"`
+	Deferred     string              `json:"deferred,omitempty"`
+	Dirty        bool                `json:"dirty"`
+	CheckedAt    time.Time           `json:"last_check"`
+	LastError    string              `json:"last_error,omitempty"`
+}
+
+// Statuses represents the states of a fleet of repositories.
+type Statuses []Status
+
+// Status returns the current state of the repository.
+// The latest version and the relationship are the ones of the last check.
+func (r *Repo) Status() Status {
+	s := Status{
+		Name:         r.displayName(),
+		Path:         r.path,
+		Local:        r.local,
+		Ahead:        r.ahead,
+		Latest:       r.remote,
+		Relationship: r.diff,
+		Deferred:     r.deferred,
+		CheckedAt:    r.checkedAt,
+	}
+	var err error
+	if r.git != nil {
+		// Only known for a Git repository.
+		if s.Remote, err = r.git.Remote(); err == nil {
+			// Never exposes the credentials of the remote.
+			s.Remote = gitflow.Redact(s.Remote)
+			s.Dirty, err = r.git.Dirty()
+		}
+	}
+	if r.lastErr != nil {
+		s.LastError = r.lastErr.Error()
+	} else if err != nil {
+		s.LastError = err.Error()
+	}
+	return s
+}
+
+// WriteJSON writes the statuses as a JSON array in w.
+func (s Statuses) WriteJSON(w io.Writer) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(s)
+}
+
+// WriteNDJSON writes the statuses in w, as newline delimited JSON: one status by line.
+func (s Statuses) WriteNDJSON(w io.Writer) error {
+	enc := json.NewEncoder(w)
+	for _, st := range s {
+		if err := enc.Encode(st); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// WriteMarkdown writes the statuses as a Markdown table in w.
+func (s Statuses) WriteMarkdown(w io.Writer) error {
+	if _, err := fmt.Fprint(w,
+		"| Path | Remote | Local | Latest | Dirty | Last check | Last error |\n",
+		"|------|--------|-------|--------|-------|------------|------------|\n",
+	); err != nil {
+		return err
+	}
+	for _, st := range s {
+		checkedAt := ""
+		if !st.CheckedAt.IsZero() {
+			checkedAt = st.CheckedAt.Format(time.RFC3339)
+		}
+		if _, err := fmt.Fprintf(w, "| %v | %v | %v | %v | %t | %v | %v |\n",
+			escapeCell(st.Path), escapeCell(st.Remote), escapeCell(st.Local), escapeCell(st.Latest),
+			st.Dirty, checkedAt, escapeCell(st.LastError),
+		); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// escapeCell returns the string usable in a Markdown table's cell.
+func escapeCell(str string) string {
+	return strings.Replace(strings.Replace(str, "|", "\\|", -1), "\n", " ", -1)
+}
@@ -0,0 +1,313 @@
+// Package tarball installs the versions from their release archives, without Git clone.
+//
+// Each version is downloaded as a gzipped tar archive, verified with the SHA256SUMS file
+// published with it, and unpacked in its own directory: <dir>/<version>. The symbolic link
+// <dir>/current is then switched atomically on it. A version already unpacked, like the previous one
+// on rollback, is only switched.
+package tarball
+
+import (
+	"archive/tar"
+	"bufio"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// Current is the name of the symbolic link on the installed version.
+const Current = "current"
+
+// DownloadTimeout is the maximum duration of a download with the default HTTP client.
+var DownloadTimeout = 5 * time.Minute
+
+// versionVar is replaced by the version in the URLs.
+const versionVar = "{version}"
+
+// Error messages.
+const (
+	errMsgDir       = "installation directory is undefined"
+	errMsgURL       = "URL of the archive or of its checksums is undefined"
+	errMsgVersion   = "invalid version"
+	errMsgChecksum  = "checksum mismatch"
+	errMsgNoSum     = "no checksum for the archive"
+	errMsgPath      = "invalid path in the archive"
+	errMsgInstalled = "no version installed"
+)
+
+// Installer installs the release archives in a directory.
+type Installer struct {
+	// Client is the HTTP client used to download the archives.
+	// If nil, a client limited to DownloadTimeout is used.
+	Client *http.Client
+	// StripComponents is the number of leading directories removed from the paths of the archive,
+	// like the option --strip-components of tar.
+	StripComponents int
+	dir             string
+	archiveURL      string
+	sumsURL         string
+}
+
+// New returns an installer in the directory of the archives downloaded at the URL,
+// verified with the checksums at the other URL. In both of them, {version} is replaced by the version.
+// @example https://example.com/app/{version}/app.tar.gz and https://example.com/app/{version}/SHA256SUMS
+func New(dir, archiveURL, sumsURL string) (*Installer, error) {
+	if dir = strings.TrimSpace(dir); dir == "" {
+		return nil, errors.New(errMsgDir)
+	}
+	if archiveURL = strings.TrimSpace(archiveURL); archiveURL == "" {
+		return nil, errors.New(errMsgURL)
+	}
+	if sumsURL = strings.TrimSpace(sumsURL); sumsURL == "" {
+		return nil, errors.New(errMsgURL)
+	}
+	return &Installer{dir: dir, archiveURL: archiveURL, sumsURL: sumsURL}, nil
+}
+
+// LocalTag returns the version targeted by the symbolic link current.
+func (i *Installer) LocalTag() (string, error) {
+	target, err := os.Readlink(filepath.Join(i.dir, Current))
+	if os.IsNotExist(err) {
+		return "", errors.New(errMsgInstalled)
+	}
+	if err != nil {
+		return "", err
+	}
+	return filepath.Base(target), nil
+}
+
+// WorkDir returns the directory of the installed version: the symbolic link current.
+func (i *Installer) WorkDir() string {
+	return filepath.Join(i.dir, Current)
+}
+
+// CheckoutTag installs the version if necessary, then switches the symbolic link current on it.
+func (i *Installer) CheckoutTag(tag string) error {
+	if tag = strings.TrimSpace(tag); tag == "" || tag != filepath.Base(tag) || strings.HasPrefix(tag, ".") {
+		return errors.New(errMsgVersion)
+	}
+	if _, err := os.Stat(filepath.Join(i.dir, tag)); os.IsNotExist(err) {
+		if err = i.install(tag); err != nil {
+			return err
+		}
+	} else if err != nil {
+		return err
+	}
+	return i.link(tag)
+}
+
+// install downloads, verifies and unpacks the archive of the version in its directory.
+func (i *Installer) install(tag string) error {
+	if err := os.MkdirAll(i.dir, 0755); err != nil {
+		return err
+	}
+	archiveURL := strings.Replace(i.archiveURL, versionVar, tag, -1)
+	f, err := ioutil.TempFile(i.dir, ".download")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(f.Name())
+	defer f.Close()
+
+	h := sha256.New()
+	if err = i.download(archiveURL, io.MultiWriter(f, h)); err != nil {
+		return err
+	}
+	sum, err := i.checksum(strings.Replace(i.sumsURL, versionVar, tag, -1), archiveName(archiveURL))
+	if err != nil {
+		return err
+	}
+	if hex.EncodeToString(h.Sum(nil)) != sum {
+		return fmt.Errorf("%s: %s", errMsgChecksum, archiveURL)
+	}
+	if _, err = f.Seek(0, io.SeekStart); err != nil {
+		return err
+	}
+	// Unpacks in a temporary directory, only renamed once complete.
+	tmp, err := ioutil.TempDir(i.dir, "."+tag)
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(tmp)
+	if err = unpack(f, tmp, i.StripComponents); err != nil {
+		return err
+	}
+	if err = os.Chmod(tmp, 0755); err != nil {
+		return err
+	}
+	return os.Rename(tmp, filepath.Join(i.dir, tag))
+}
+
+// link switches atomically the symbolic link current on the version.
+func (i *Installer) link(tag string) error {
+	tmp := filepath.Join(i.dir, "."+Current)
+	os.Remove(tmp)
+	if err := os.Symlink(tag, tmp); err != nil {
+		return err
+	}
+	return os.Rename(tmp, filepath.Join(i.dir, Current))
+}
+
+// checksum returns the SHA256 checksum of the file listed in the SHA256SUMS file at the URL.
+func (i *Installer) checksum(sumsURL, name string) (string, error) {
+	var buf strings.Builder
+	if err := i.download(sumsURL, &buf); err != nil {
+		return "", err
+	}
+	sc := bufio.NewScanner(strings.NewReader(buf.String()))
+	for sc.Scan() {
+		// Format of sha256sum: checksum, then the name, prefixed by * in binary mode.
+		fields := strings.Fields(sc.Text())
+		if len(fields) == 2 && strings.TrimPrefix(fields[1], "*") == name {
+			return strings.ToLower(fields[0]), nil
+		}
+	}
+	return "", fmt.Errorf("%s: %s", errMsgNoSum, name)
+}
+
+// download writes in w the content at the URL.
+func (i *Installer) download(rawURL string, w io.Writer) error {
+	client := i.Client
+	if client == nil {
+		client = &http.Client{Timeout: DownloadTimeout}
+	}
+	resp, err := client.Get(rawURL)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("%s: %s", rawURL, resp.Status)
+	}
+	_, err = io.Copy(w, resp.Body)
+	return err
+}
+
+// archiveName returns the name of the file at the URL, as listed in the SHA256SUMS file.
+func archiveName(rawURL string) string {
+	if u, err := url.Parse(rawURL); err == nil {
+		return path.Base(u.Path)
+	}
+	return path.Base(rawURL)
+}
+
+// unpack extracts the gzipped tar archive in the directory, without the leading directories.
+// It refuses any path outside of the directory, as well as any write through a symbolic link.
+func unpack(r io.Reader, dir string, strip int) error {
+	gz, err := gzip.NewReader(r)
+	if err != nil {
+		return err
+	}
+	defer gz.Close()
+
+	var links []string
+	tr := tar.NewReader(gz)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			return resolved(dir, links)
+		}
+		if err != nil {
+			return err
+		}
+		name := path.Clean(strings.TrimPrefix(hdr.Name, "./"))
+		if path.IsAbs(name) || name == ".." || strings.HasPrefix(name, "../") {
+			return fmt.Errorf("%s: %s", errMsgPath, hdr.Name)
+		}
+		parts := strings.Split(name, "/")
+		if len(parts) <= strip {
+			continue
+		}
+		target := filepath.Join(dir, filepath.FromSlash(strings.Join(parts[strip:], "/")))
+		if symlinked(dir, target) {
+			return fmt.Errorf("%s: %s", errMsgPath, hdr.Name)
+		}
+		switch hdr.Typeflag {
+		case tar.TypeDir:
+			err = os.MkdirAll(target, 0755)
+		case tar.TypeReg:
+			err = writeFile(target, tr, os.FileMode(hdr.Mode).Perm())
+		case tar.TypeSymlink:
+			if filepath.IsAbs(hdr.Linkname) || !within(dir, filepath.Join(filepath.Dir(target), hdr.Linkname)) {
+				return fmt.Errorf("%s: %s", errMsgPath, hdr.Linkname)
+			}
+			if err = os.MkdirAll(filepath.Dir(target), 0755); err == nil {
+				err = os.Symlink(hdr.Linkname, target)
+			}
+			links = append(links, target)
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
+
+// symlinked returns true if the path or one of its parents inside the directory is a symbolic link.
+func symlinked(dir, p string) bool {
+	rel, err := filepath.Rel(dir, p)
+	if err != nil {
+		return true
+	}
+	for _, part := range strings.Split(rel, string(filepath.Separator)) {
+		dir = filepath.Join(dir, part)
+		fi, err := os.Lstat(dir)
+		if os.IsNotExist(err) {
+			return false
+		}
+		if err != nil || fi.Mode()&os.ModeSymlink != 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// resolved returns an error if one of the symbolic links resolves outside of the directory,
+// like a chain of links whose each one seems to be inside.
+func resolved(dir string, links []string) error {
+	dir, err := filepath.EvalSymlinks(dir)
+	if err != nil {
+		return err
+	}
+	for _, name := range links {
+		p, err := filepath.EvalSymlinks(name)
+		if os.IsNotExist(err) {
+			continue
+		}
+		if err != nil || !within(dir, p) {
+			return fmt.Errorf("%s: %s", errMsgPath, filepath.Base(name))
+		}
+	}
+	return nil
+}
+
+// within returns true if the path is inside the directory.
+func within(dir, p string) bool {
+	rel, err := filepath.Rel(dir, p)
+	return err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator))
+}
+
+// writeFile writes the content of r in the file, created with its parent directories.
+func writeFile(name string, r io.Reader, perm os.FileMode) error {
+	if err := os.MkdirAll(filepath.Dir(name), 0755); err != nil {
+		return err
+	}
+	f, err := os.OpenFile(name, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, perm)
+	if err != nil {
+		return err
+	}
+	if _, err = io.Copy(f, r); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
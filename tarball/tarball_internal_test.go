@@ -0,0 +1,106 @@
+package tarball
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// entry is a file or a symbolic link of an archive.
+type entry struct {
+	name, link string
+}
+
+// archiveOf returns a gzipped tar archive with these entries.
+func archiveOf(t *testing.T, entries ...entry) *bytes.Buffer {
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	tw := tar.NewWriter(gz)
+	for _, e := range entries {
+		hdr := &tar.Header{Name: e.name, Mode: 0644, Typeflag: tar.TypeReg, Size: int64(len(e.name))}
+		if e.link != "" {
+			hdr = &tar.Header{Name: e.name, Linkname: e.link, Mode: 0777, Typeflag: tar.TypeSymlink}
+		}
+		if err := tw.WriteHeader(hdr); err != nil {
+			t.Fatalf("Unable to write archive, received: %v", err)
+		}
+		if e.link == "" {
+			tw.Write([]byte(e.name))
+		}
+	}
+	tw.Close()
+	gz.Close()
+	return &buf
+}
+
+func TestUnpack(t *testing.T) {
+	var dt = []struct {
+		entries []entry
+		ok      bool
+	}{
+		{entries: []entry{{name: "bin/app"}, {name: "app", link: "bin/app"}}, ok: true},
+		{entries: []entry{{name: "lib/a.so.1"}, {name: "lib/a.so", link: "a.so.1"}, {name: "a", link: "lib/a.so"}}, ok: true},
+		{entries: []entry{{name: "evil", link: "../evil"}}},
+		{entries: []entry{{name: "d1", link: "."}, {name: "d1/d2", link: ".."}}},
+		{entries: []entry{{name: "d1", link: "."}, {name: "d1/evil"}}},
+		{entries: []entry{{name: "s", link: "."}, {name: "evil", link: "s/.."}}},
+		{entries: []entry{{name: "app", link: "bin/app"}, {name: "app"}}},
+	}
+	for i, tt := range dt {
+		root, err := ioutil.TempDir("", "gitup")
+		if err != nil {
+			t.Fatalf("Unable to create directory, received: %v", err)
+		}
+		dir := filepath.Join(root, "app")
+		if err = os.Mkdir(dir, 0755); err != nil {
+			t.Fatalf("Unable to create directory, received: %v", err)
+		}
+		err = unpack(archiveOf(t, tt.entries...), dir, 0)
+		if tt.ok != (err == nil) {
+			t.Errorf("%d. Expected success: %t, received: %v", i, tt.ok, err)
+		}
+		if _, err = os.Stat(filepath.Join(root, "evil")); err == nil {
+			t.Errorf("%d. Expected no file outside of the directory", i)
+		}
+		os.RemoveAll(root)
+	}
+}
+
+// TestInstaller_Timeout tests that a download never waits for an unresponsive server.
+func TestInstaller_Timeout(t *testing.T) {
+	timeout := DownloadTimeout
+	DownloadTimeout = 50 * time.Millisecond
+	defer func() { DownloadTimeout = timeout }()
+
+	done := make(chan struct{})
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-done
+	}))
+	defer srv.Close()
+	// Releases the handler before closing the server.
+	defer close(done)
+
+	dir, err := ioutil.TempDir("", "gitup")
+	if err != nil {
+		t.Fatalf("Unable to create directory, received: %v", err)
+	}
+	defer os.RemoveAll(dir)
+	i, err := New(dir, srv.URL+"/{version}/app.tar.gz", srv.URL+"/{version}/SHA256SUMS")
+	if err != nil {
+		t.Fatalf("Expected no error, received: %v", err)
+	}
+	start := time.Now()
+	if err = i.CheckoutTag("v1.0.0"); err == nil {
+		t.Error("Expected error with an unresponsive server")
+	}
+	if d := time.Since(start); d > time.Second {
+		t.Errorf("Expected download canceled, received: %v", d)
+	}
+}
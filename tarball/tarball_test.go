@@ -0,0 +1,140 @@
+package tarball_test
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/rvflash/gitup"
+	"github.com/rvflash/gitup/tarball"
+)
+
+// FakeSource lists the published versions.
+type FakeSource []string
+
+// LastTag implements the gitup.Source interface.
+func (s FakeSource) LastTag() (string, error) { return s[len(s)-1], nil }
+
+// Tags implements the gitup.Source interface.
+func (s FakeSource) Tags() ([]string, error) { return s, nil }
+
+// TagDate implements the gitup.Source interface.
+func (s FakeSource) TagDate(string) (time.Time, error) { return time.Time{}, nil }
+
+// archive returns a gzipped tar archive with these files, under the directory app.
+func archive(t *testing.T, files ...string) []byte {
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	tw := tar.NewWriter(gz)
+	for _, name := range files {
+		content := "content of " + name
+		hdr := &tar.Header{Name: "app/" + name, Mode: 0644, Size: int64(len(content)), Typeflag: tar.TypeReg}
+		if err := tw.WriteHeader(hdr); err != nil {
+			t.Fatalf("Unable to write archive, received: %v", err)
+		}
+		tw.Write([]byte(content))
+	}
+	tw.Close()
+	gz.Close()
+	return buf.Bytes()
+}
+
+// newReleases serves for each version its archive and its SHA256SUMS file.
+// The checksum of v1.2.0 does not match, the archive of v1.3.0 has a path outside of the directory.
+func newReleases(t *testing.T, downloads *int) *httptest.Server {
+	files := make(map[string][]byte)
+	for _, tag := range []string{"v1.0.0", "v1.1.0", "v1.2.0", "v1.3.0"} {
+		data := archive(t, "VERSION", "bin/app")
+		if tag == "v1.3.0" {
+			data = archive(t, "../../evil")
+		}
+		sum := sha256.Sum256(data)
+		if tag == "v1.2.0" {
+			sum = sha256.Sum256(nil)
+		}
+		files["/"+tag+"/app.tar.gz"] = data
+		files["/"+tag+"/SHA256SUMS"] = []byte(hex.EncodeToString(sum[:]) + "  *app.tar.gz\n" + strings.Repeat("0", 64) + "  other.tar.gz\n")
+	}
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		data, ok := files[r.URL.Path]
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		if strings.HasSuffix(r.URL.Path, ".tar.gz") {
+			*downloads++
+		}
+		w.Write(data)
+	}))
+}
+
+// TestInstaller tests the installation, the update and the rollback through a repository.
+func TestInstaller(t *testing.T) {
+	var downloads int
+	srv := newReleases(t, &downloads)
+	defer srv.Close()
+
+	dir, err := ioutil.TempDir(os.TempDir(), "tarball")
+	if err != nil {
+		t.Fatalf("Unable to create directory, received: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	if _, err = tarball.New(dir, srv.URL+"/{version}/app.tar.gz", ""); err == nil {
+		t.Error("Expected error without checksums")
+	}
+	i, err := tarball.New(dir, srv.URL+"/{version}/app.tar.gz", srv.URL+"/{version}/SHA256SUMS")
+	if err != nil {
+		t.Fatalf("Expected no error, received: %v", err)
+	}
+	i.StripComponents = 1
+	if _, err = i.LocalTag(); err == nil {
+		t.Error("Expected error without installed version")
+	}
+	if err = i.CheckoutTag("v1.0.0"); err != nil {
+		t.Fatalf("Expected no error, received: %v", err)
+	}
+	r, err := gitup.NewRepoFrom(dir, FakeSource{"v1.0.0", "v1.1.0"}, i)
+	if err != nil {
+		t.Fatalf("Expected no error, received: %v", err)
+	}
+	s := gitup.UpdateStrategy{}
+	s.AddStrategy(gitup.MinorVersion, gitup.Auto)
+	if err = r.Update(s); err != nil {
+		t.Fatalf("Expected no error, received: %v", err)
+	}
+	buf, err := ioutil.ReadFile(filepath.Join(dir, tarball.Current, "bin", "app"))
+	if err != nil || string(buf) != "content of bin/app" {
+		t.Errorf("Expected unpacked archive of v1.1.0, received: %q, %v", buf, err)
+	}
+	if err = r.Rollback(); err != nil {
+		t.Fatalf("Expected no error, received: %v", err)
+	}
+	if tag, err := i.LocalTag(); err != nil || tag != "v1.0.0" || downloads != 2 {
+		t.Errorf("Expected rollback on v1.0.0 without download, received: %v, %v, %d downloads", tag, err, downloads)
+	}
+	for _, tag := range []string{"../v1.0.0", " ", "v1.2.0", "v1.3.0", "v9.9.9"} {
+		if err = i.CheckoutTag(tag); err == nil {
+			t.Errorf("Expected error with %q", tag)
+		}
+	}
+	if files, _ := filepath.Glob(filepath.Join(dir, "*")); len(files) != 3 {
+		t.Errorf("Expected only the 2 installed versions and the link, received: %v", files)
+	}
+	if tag, err := i.LocalTag(); err != nil || tag != "v1.0.0" {
+		t.Errorf("Expected current version unchanged after failures, received: %v, %v", tag, err)
+	}
+	if _, err = os.Stat(filepath.Join(filepath.Dir(dir), "evil")); err == nil {
+		t.Error("Expected no file outside of the directory")
+	}
+}
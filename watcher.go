@@ -0,0 +1,237 @@
+package gitup
+
+import (
+	"context"
+	"log"
+	"math/rand"
+	"sync"
+	"time"
+
+	"github.com/rvflash/gitup/internal/cron"
+)
+
+// Default delays of the watcher.
+const (
+	DefaultInterval = time.Hour
+	DefaultRetry    = 30 * time.Second
+)
+
+// Watcher periodically checks a set of configured repositories and applies their update strategy.
+// A deferred update is applied at the opening of the next maintenance window. On failure, the next check of the repository is delayed with an exponential back off.
+type Watcher struct {
+	// ErrorLog specifies an optional logger for errors.
+	// If nil, logging is done via the log package's standard logger.
+	ErrorLog *log.Logger
+
+	load     func() (*Config, error)
+	manager  func(c *Config) (*Manager, error)
+	reload   chan struct{}
+	mu       sync.RWMutex
+	current  *Manager
+	triggers map[string]chan struct{}
+}
+
+// schedule defines when to check a repository.
+type schedule struct {
+	every         time.Duration
+	cron          *cron.Schedule
+	jitter, retry time.Duration
+}
+
+// NewWatcher returns a watcher of the repositories configured in this JSON file.
+func NewWatcher(configPath string) *Watcher {
+	return &Watcher{
+		load: func() (*Config, error) {
+			return LoadConfig(configPath)
+		},
+		manager: (*Config).Manager,
+		reload:  make(chan struct{}, 1),
+	}
+}
+
+// Reload asks the watcher to read its configuration again.
+// If the new one is invalid, the watcher keeps the previous one.
+func (w *Watcher) Reload() {
+	select {
+	case w.reload <- struct{}{}:
+	default:
+		// A reload is already pending.
+	}
+}
+
+// Run watches the repositories until the context is done.
+// A check or an update in progress is always completed before returning.
+func (w *Watcher) Run(ctx context.Context) error {
+	c, err := w.load()
+	if err != nil {
+		return err
+	}
+	m, s, err := w.setup(c)
+	if err != nil {
+		return err
+	}
+	for {
+		triggers := make(map[string]chan struct{})
+		for _, name := range m.Names() {
+			triggers[name] = make(chan struct{}, 1)
+		}
+		w.mu.Lock()
+		w.current, w.triggers = m, triggers
+		w.mu.Unlock()
+		wctx, cancel := context.WithCancel(ctx)
+		var wg sync.WaitGroup
+		for _, name := range m.Names() {
+			wg.Add(1)
+			go func(m *Manager, name string, s schedule, trigger <-chan struct{}) {
+				defer wg.Done()
+				w.watch(wctx, m, name, s, trigger)
+			}(m, name, s, triggers[name])
+		}
+		prev := m
+		m, s = w.wait(ctx, m, s)
+		cancel()
+		wg.Wait()
+		if ctx.Err() != nil {
+			return nil
+		}
+		// Once the previous repositories are no longer watched, their state is carried over.
+		m.inherit(prev)
+	}
+}
+
+// Manager returns the manager of the repositories currently watched, nil before the run.
+func (w *Watcher) Manager() *Manager {
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+
+	return w.current
+}
+
+// Trigger asks the watcher to check immediately the named repository and to apply its strategy.
+func (w *Watcher) Trigger(name string) error {
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+
+	trigger, ok := w.triggers[name]
+	if !ok {
+		return ErrUnknownRepo
+	}
+	select {
+	case trigger <- struct{}{}:
+	default:
+		// A check is already pending.
+	}
+	return nil
+}
+
+// wait blocks until the context is done or a new valid configuration is loaded.
+func (w *Watcher) wait(ctx context.Context, m *Manager, s schedule) (*Manager, schedule) {
+	for {
+		select {
+		case <-ctx.Done():
+			return m, s
+		case <-w.reload:
+			c, err := w.load()
+			if err == nil {
+				var nm *Manager
+				var ns schedule
+				if nm, ns, err = w.setup(c); err == nil {
+					return nm, ns
+				}
+			}
+			w.logf("gitup: reload failed, keeps the previous configuration: %v", err)
+		}
+	}
+}
+
+// setup returns the manager and the schedule of the configuration.
+func (w *Watcher) setup(c *Config) (m *Manager, s schedule, err error) {
+	s = schedule{
+		every:  time.Duration(c.Interval),
+		jitter: time.Duration(c.Jitter),
+		retry:  time.Duration(c.Retry),
+	}
+	if c.Cron != "" {
+		if s.cron, err = cron.Parse(c.Cron); err != nil {
+			return
+		}
+	} else if s.every <= 0 {
+		s.every = DefaultInterval
+	}
+	if s.retry <= 0 {
+		s.retry = DefaultRetry
+	}
+	m, err = w.manager(c)
+	return
+}
+
+// watch checks the repository on schedule or on trigger until the context is done.
+func (w *Watcher) watch(ctx context.Context, m *Manager, name string, s schedule, trigger <-chan struct{}) {
+	var failures uint
+	delay := s.delay(time.Now(), 0)
+	if s.cron == nil {
+		// First check as soon as possible.
+		delay = s.spread()
+	}
+	for {
+		t := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			t.Stop()
+			return
+		case <-trigger:
+			t.Stop()
+		case <-t.C:
+		}
+		if err := m.Apply(name); err != nil {
+			failures++
+			w.logf("gitup: %v: %v", name, err)
+		} else {
+			failures = 0
+		}
+		now := time.Now()
+		delay = s.delay(now, failures)
+		if next := m.nextWindow(name, now); !next.IsZero() && next.Sub(now) < delay {
+			// Applies the deferred update as soon as the maintenance window opens.
+			delay = next.Sub(now) + s.spread()
+		}
+	}
+}
+
+// delay returns the duration to wait before the next check.
+// After failures, it doubles the retry delay at each one, without exceeding the normal delay.
+func (s schedule) delay(now time.Time, failures uint) time.Duration {
+	d := s.every
+	if s.cron != nil {
+		if d = DefaultInterval; !s.cron.Next(now).IsZero() {
+			d = s.cron.Next(now).Sub(now)
+		}
+	}
+	if failures > 0 {
+		retry := s.retry
+		for i := uint(1); i < failures && retry < d; i++ {
+			retry *= 2
+		}
+		if retry < d {
+			d = retry
+		}
+	}
+	return d + s.spread()
+}
+
+// spread returns a random duration up to the jitter.
+func (s schedule) spread() time.Duration {
+	if s.jitter <= 0 {
+		return 0
+	}
+	return time.Duration(rand.Int63n(int64(s.jitter)))
+}
+
+// logf logs the error with the logger of the watcher.
+func (w *Watcher) logf(format string, args ...interface{}) {
+	if w.ErrorLog != nil {
+		w.ErrorLog.Printf(format, args...)
+	} else {
+		log.Printf(format, args...)
+	}
+}
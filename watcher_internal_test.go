@@ -0,0 +1,176 @@
+package gitup
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"log"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/rvflash/gitup/internal/cron"
+)
+
+// CountingGitFlow extends FakeGitFlow to count the fetches.
+type CountingGitFlow struct {
+	FakeGitFlow
+	mu      sync.Mutex
+	fetches int
+}
+
+// LastTag mocks the gitflow's method LastTag() on CountingGitFlow struct.
+func (r *CountingGitFlow) LastTag() (string, error) {
+	r.mu.Lock()
+	r.fetches++
+	r.mu.Unlock()
+	return r.FakeGitFlow.LastTag()
+}
+
+// count returns the number of fetches.
+func (r *CountingGitFlow) count() int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.fetches
+}
+
+var delayTests = []struct {
+	every, retry time.Duration // input
+	failures     uint
+	delay        time.Duration // expected result
+}{
+	{time.Hour, time.Minute, 0, time.Hour},
+	{time.Hour, time.Minute, 1, time.Minute},
+	{time.Hour, time.Minute, 2, 2 * time.Minute},
+	{time.Hour, time.Minute, 4, 8 * time.Minute},
+	{time.Hour, time.Minute, 7, time.Hour},
+	{time.Hour, time.Minute, 200, time.Hour},
+}
+
+// TestSchedule_Delay tests the exponential back off on failures.
+func TestSchedule_Delay(t *testing.T) {
+	for _, dt := range delayTests {
+		s := schedule{every: dt.every, retry: dt.retry}
+		if d := s.delay(time.Now(), dt.failures); d != dt.delay {
+			t.Errorf("Expected delay %v after %d failures, received: %v", dt.delay, dt.failures, d)
+		}
+	}
+	// With jitter.
+	s := schedule{every: time.Hour, jitter: time.Minute}
+	for i := 0; i < 10; i++ {
+		if d := s.delay(time.Now(), 0); d < time.Hour || d >= time.Hour+time.Minute {
+			t.Errorf("Expected delay with jitter, received: %v", d)
+		}
+	}
+	// With cron expression.
+	s = schedule{cron: mustParseCron(t, "0 * * * *")}
+	now := time.Date(2017, 6, 1, 10, 45, 0, 0, time.UTC)
+	if d := s.delay(now, 0); d != 15*time.Minute {
+		t.Errorf("Expected delay until the next hour, received: %v", d)
+	}
+}
+
+// TestWatcher_Run tests the periodic checks and the reload of the configuration.
+func TestWatcher_Run(t *testing.T) {
+	git := &CountingGitFlow{FakeGitFlow: FakeGitFlow{localTag: "v1.0.0", remoteTag: "v1.0.0"}}
+	var mu sync.Mutex
+	var loads, setups int
+	w := &Watcher{
+		ErrorLog: log.New(ioutil.Discard, "", 0),
+		load: func() (*Config, error) {
+			mu.Lock()
+			defer mu.Unlock()
+			if loads++; loads == 3 {
+				return nil, errors.New(errMsgFake)
+			}
+			return &Config{Interval: Duration(5 * time.Millisecond)}, nil
+		},
+		manager: func(c *Config) (*Manager, error) {
+			mu.Lock()
+			setups++
+			mu.Unlock()
+			m := NewManager()
+			return m, m.Add("app", &Repo{git: git}, UpdateStrategy{})
+		},
+		reload: make(chan struct{}, 1),
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	go func() {
+		time.Sleep(20 * time.Millisecond)
+		w.Reload()
+		time.Sleep(20 * time.Millisecond)
+		w.Reload()
+	}()
+	if err := w.Run(ctx); err != nil {
+		t.Errorf("Expected no error, received: %v", err)
+	}
+	if n := git.count(); n < 5 {
+		t.Errorf("Expected periodic checks, received: %d", n)
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	if loads != 3 || setups != 2 {
+		t.Errorf("Expected 3 loads with 1 invalid, received: %d loads, %d setups", loads, setups)
+	}
+}
+
+// TestWatcher_Run_InvalidConfig tests that Run fails without valid configuration.
+func TestWatcher_Run_InvalidConfig(t *testing.T) {
+	if err := NewWatcher("/unknown/gitup.json").Run(context.Background()); err == nil {
+		t.Error("Expected error with unknown configuration file")
+	}
+	w := NewWatcher("")
+	w.load = func() (*Config, error) {
+		return &Config{Cron: "* *"}, nil
+	}
+	if err := w.Run(context.Background()); err == nil {
+		t.Error("Expected error with invalid cron expression")
+	}
+}
+
+// mustParseCron returns the schedule of the cron expression.
+func mustParseCron(t *testing.T, expr string) *cron.Schedule {
+	s, err := cron.Parse(expr)
+	if err != nil {
+		t.Fatalf("Unable to parse cron expression, received: %v", err)
+	}
+	return s
+}
+
+// TestWatcher_Trigger tests that a trigger checks the repository before its next schedule.
+func TestWatcher_Trigger(t *testing.T) {
+	git := &CountingGitFlow{FakeGitFlow: FakeGitFlow{localTag: "v1.0.0", remoteTag: "v1.0.0"}}
+	w := NewWatcher("")
+	w.load = func() (*Config, error) {
+		return &Config{Interval: Duration(time.Hour)}, nil
+	}
+	w.manager = func(c *Config) (*Manager, error) {
+		m := NewManager()
+		return m, m.Add("app", &Repo{git: git}, UpdateStrategy{})
+	}
+	if err := w.Trigger("app"); err != ErrUnknownRepo {
+		t.Errorf("Expected unknown repository before running, received: %v", err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	go w.Run(ctx)
+
+	wait := func(n int) {
+		for git.count() < n && ctx.Err() == nil {
+			time.Sleep(time.Millisecond)
+		}
+	}
+	// First check as soon as possible.
+	wait(1)
+	if err := w.Trigger("app"); err != nil {
+		t.Errorf("Expected no error, received: %v", err)
+	}
+	if err := w.Trigger("api"); err != ErrUnknownRepo {
+		t.Errorf("Expected unknown repository, received: %v", err)
+	}
+	wait(2)
+	if n := git.count(); n != 2 {
+		t.Errorf("Expected 2 checks with the trigger, received: %d", n)
+	}
+}
@@ -0,0 +1,210 @@
+// Package webhook receives the push and tag webhooks of GitHub, GitLab and Gitea
+// to trigger an immediate check of the matching repositories.
+//
+// The signature of each request is verified with the shared secret: the HMAC SHA-256 of the body
+// in the header X-Hub-Signature-256 for GitHub or X-Gitea-Signature for Gitea, the secret itself
+// in the header X-Gitlab-Token for GitLab. A repository matches when one of the URLs of the payload
+// is its remote.
+package webhook
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"strings"
+
+	"github.com/rvflash/gitup"
+)
+
+// Error messages.
+const (
+	errMsgSignature   = "invalid signature"
+	errMsgNoSecret    = "secret required to verify the signatures"
+	errMsgPayload     = "invalid payload"
+	errMsgNoRepo      = "no matching repository"
+	errMsgMethod      = "method not allowed"
+	errMsgUnavailable = "no repository available"
+)
+
+// maxBodySize is the maximum size of a payload.
+const maxBodySize = 1 << 20
+
+// Headers of each forge.
+const (
+	githubEvent     = "X-GitHub-Event"
+	githubSignature = "X-Hub-Signature-256"
+	giteaEvent      = "X-Gitea-Event"
+	giteaSignature  = "X-Gitea-Signature"
+	gitlabEvent     = "X-Gitlab-Event"
+	gitlabToken     = "X-Gitlab-Token"
+)
+
+// Trigger checks immediately a repository, like gitup.Watcher.
+type Trigger interface {
+	Manager() *gitup.Manager
+	Trigger(name string) error
+}
+
+// Handler receives the webhooks.
+type Handler struct {
+	trigger Trigger
+	secret  string
+}
+
+// Result is the response listing the triggered repositories.
+type Result struct {
+	Triggered []string `json:"triggered"`
+}
+
+// payload contains the URLs of the repository sent by each forge.
+type payload struct {
+	Repository struct {
+		CloneURL string `json:"clone_url"`
+		SSHURL   string `json:"ssh_url"`
+		HTMLURL  string `json:"html_url"`
+	} `json:"repository"`
+	Project struct {
+		HTTPURL string `json:"git_http_url"`
+		SSHURL  string `json:"git_ssh_url"`
+		WebURL  string `json:"web_url"`
+	} `json:"project"`
+}
+
+// errorResult is the response on error.
+type errorResult struct {
+	Error string `json:"error"`
+}
+
+// New returns a handler of webhooks signed with the secret, triggering the checks on t.
+// It returns an error if the secret is empty, since anyone could sign the requests.
+func New(t Trigger, secret string) (*Handler, error) {
+	if strings.TrimSpace(secret) == "" {
+		return nil, errors.New(errMsgNoSecret)
+	}
+	return &Handler{trigger: t, secret: secret}, nil
+}
+
+// ServeHTTP implements the http.Handler interface.
+func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost)
+		writeJSON(w, http.StatusMethodNotAllowed, errorResult{errMsgMethod})
+		return
+	}
+	body, err := ioutil.ReadAll(http.MaxBytesReader(w, r.Body, maxBodySize))
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, errorResult{errMsgPayload})
+		return
+	}
+	event, ok := h.verify(r.Header, body)
+	if !ok {
+		writeJSON(w, http.StatusUnauthorized, errorResult{errMsgSignature})
+		return
+	}
+	if !isTagEvent(event) {
+		// Like a ping, nothing to do.
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+	var p payload
+	if err = json.Unmarshal(body, &p); err != nil {
+		writeJSON(w, http.StatusBadRequest, errorResult{errMsgPayload})
+		return
+	}
+	m := h.trigger.Manager()
+	if m == nil {
+		writeJSON(w, http.StatusServiceUnavailable, errorResult{errMsgUnavailable})
+		return
+	}
+	res := Result{Triggered: []string{}}
+	remotes := m.Remotes()
+	for _, name := range m.Names() {
+		if p.matches(remotes[name]) && h.trigger.Trigger(name) == nil {
+			res.Triggered = append(res.Triggered, name)
+		}
+	}
+	if len(res.Triggered) == 0 {
+		writeJSON(w, http.StatusNotFound, errorResult{errMsgNoRepo})
+		return
+	}
+	writeJSON(w, http.StatusAccepted, res)
+}
+
+// verify returns the event of the request if its signature is valid.
+func (h *Handler) verify(header http.Header, body []byte) (event string, ok bool) {
+	switch {
+	case header.Get(githubEvent) != "":
+		sig := strings.TrimPrefix(header.Get(githubSignature), "sha256=")
+		return header.Get(githubEvent), h.validMAC(sig, body)
+	case header.Get(giteaEvent) != "":
+		return header.Get(giteaEvent), h.validMAC(header.Get(giteaSignature), body)
+	case header.Get(gitlabEvent) != "":
+		token := header.Get(gitlabToken)
+		return header.Get(gitlabEvent), subtle.ConstantTimeCompare([]byte(token), []byte(h.secret)) == 1
+	}
+	return "", false
+}
+
+// validMAC returns true if the hexadecimal signature is the HMAC SHA-256 of the body with the secret.
+func (h *Handler) validMAC(sig string, body []byte) bool {
+	got, err := hex.DecodeString(sig)
+	if err != nil {
+		return false
+	}
+	mac := hmac.New(sha256.New, []byte(h.secret))
+	mac.Write(body)
+	return hmac.Equal(got, mac.Sum(nil))
+}
+
+// isTagEvent returns true if the event can concern a new tag.
+func isTagEvent(event string) bool {
+	switch event {
+	case "push", "create", "Push Hook", "Tag Push Hook":
+		return true
+	}
+	return false
+}
+
+// matches returns true if one of the URLs of the payload is the remote.
+func (p payload) matches(remote string) bool {
+	if remote = normalize(remote); remote == "" {
+		return false
+	}
+	for _, url := range []string{
+		p.Repository.CloneURL, p.Repository.SSHURL, p.Repository.HTMLURL,
+		p.Project.HTTPURL, p.Project.SSHURL, p.Project.WebURL,
+	} {
+		if normalize(url) == remote {
+			return true
+		}
+	}
+	return false
+}
+
+// normalize returns the host and path of a Git URL, without scheme, user or .git suffix.
+// @example git@github.com:rvflash/gitup.git => github.com/rvflash/gitup
+func normalize(url string) string {
+	url = strings.ToLower(strings.TrimSpace(url))
+	if pos := strings.Index(url, "://"); pos > -1 {
+		url = url[pos+3:]
+	} else if pos = strings.Index(url, ":"); pos > -1 {
+		// SCP-like syntax.
+		url = url[:pos] + "/" + url[pos+1:]
+	}
+	if pos := strings.Index(url, "@"); pos > -1 {
+		url = url[pos+1:]
+	}
+	return strings.TrimSuffix(strings.TrimSuffix(url, "/"), ".git")
+}
+
+// writeJSON writes the value as JSON with the status code.
+func writeJSON(w http.ResponseWriter, code int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	json.NewEncoder(w).Encode(v)
+}
@@ -0,0 +1,150 @@
+package webhook_test
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"os/exec"
+	"strings"
+	"testing"
+
+	"github.com/rvflash/gitup"
+	"github.com/rvflash/gitup/webhook"
+)
+
+const (
+	secretTest = "s3cr3t"
+	remoteTest = "https://github.com/rvflash/app.git"
+)
+
+// FakeTrigger records the triggered repositories.
+type FakeTrigger struct {
+	manager   *gitup.Manager
+	triggered []string
+}
+
+// Manager implements the webhook.Trigger interface.
+func (t *FakeTrigger) Manager() *gitup.Manager {
+	return t.manager
+}
+
+// Trigger implements the webhook.Trigger interface.
+func (t *FakeTrigger) Trigger(name string) error {
+	t.triggered = append(t.triggered, name)
+	return nil
+}
+
+var webhookTests = []struct {
+	method  string   // input
+	header  []string // pairs of key, value
+	body    string
+	code    int // expected result
+	trigger string
+}{
+	{"GET", nil, "", http.StatusMethodNotAllowed, ""},
+	{"POST", nil, `{}`, http.StatusUnauthorized, ""},
+	{"POST", []string{"X-GitHub-Event", "push", "X-Hub-Signature-256", "sha256=00"}, `{}`, http.StatusUnauthorized, ""},
+	{"POST", []string{"X-GitHub-Event", "ping", "X-Hub-Signature-256", "sha256=" + sign(`{}`)}, `{}`, http.StatusNoContent, ""},
+	{"POST", []string{"X-GitHub-Event", "push", "X-Hub-Signature-256", "sha256=" + sign(`{`)}, `{`, http.StatusBadRequest, ""},
+	{
+		"POST", []string{"X-GitHub-Event", "push", "X-Hub-Signature-256", "sha256=" + sign(`{"repository":{"clone_url":"https://github.com/rvflash/api.git"}}`)},
+		`{"repository":{"clone_url":"https://github.com/rvflash/api.git"}}`, http.StatusNotFound, "",
+	},
+	{
+		"POST", []string{"X-GitHub-Event", "push", "X-Hub-Signature-256", "sha256=" + sign(`{"repository":{"ssh_url":"git@github.com:rvflash/app.git"}}`)},
+		`{"repository":{"ssh_url":"git@github.com:rvflash/app.git"}}`, http.StatusAccepted, "app",
+	},
+	{
+		"POST", []string{"X-Gitea-Event", "create", "X-Gitea-Signature", sign(`{"repository":{"html_url":"https://GitHub.com/rvflash/app"}}`)},
+		`{"repository":{"html_url":"https://GitHub.com/rvflash/app"}}`, http.StatusAccepted, "app",
+	},
+	{"POST", []string{"X-Gitlab-Event", "Tag Push Hook", "X-Gitlab-Token", "wrong"}, `{}`, http.StatusUnauthorized, ""},
+	{
+		"POST", []string{"X-Gitlab-Event", "Tag Push Hook", "X-Gitlab-Token", secretTest},
+		`{"project":{"git_http_url":"https://github.com/rvflash/app.git"}}`, http.StatusAccepted, "app",
+	},
+}
+
+// sign returns the HMAC SHA-256 of the body with the secret.
+func sign(body string) string {
+	mac := hmac.New(sha256.New, []byte(secretTest))
+	mac.Write([]byte(body))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// newGitRepo returns a Git repository with the remote.
+func newGitRepo(t *testing.T) (dir string) {
+	var err error
+	if dir, err = ioutil.TempDir(os.TempDir(), "gitup"); err != nil {
+		t.Fatalf("Unable to create directory, received: %v", err)
+	}
+	for _, args := range [][]string{{"init", "-q"}, {"remote", "add", "origin", remoteTest}} {
+		if out, err := exec.Command("git", append([]string{"-C", dir}, args...)...).CombinedOutput(); err != nil {
+			t.Fatalf("Unable to run git %v, received: %v, %s", args, err, out)
+		}
+	}
+	return
+}
+
+// TestHandler_ServeHTTP tests the signatures and the payloads of each forge.
+func TestHandler_ServeHTTP(t *testing.T) {
+	dir := newGitRepo(t)
+	defer os.RemoveAll(dir)
+
+	r, err := gitup.NewRepo(dir)
+	if err != nil {
+		t.Fatalf("Expected no error, received: %v", err)
+	}
+	m := gitup.NewManager()
+	if err = m.Add("app", r, gitup.UpdateStrategy{}); err != nil {
+		t.Fatalf("Expected no error, received: %v", err)
+	}
+	for _, wt := range webhookTests {
+		tr := &FakeTrigger{manager: m}
+		req := httptest.NewRequest(wt.method, "/hooks", strings.NewReader(wt.body))
+		for i := 0; i+1 < len(wt.header); i += 2 {
+			req.Header.Set(wt.header[i], wt.header[i+1])
+		}
+		rec := httptest.NewRecorder()
+		h, err := webhook.New(tr, secretTest)
+		if err != nil {
+			t.Fatalf("Expected no error, received: %v", err)
+		}
+		h.ServeHTTP(rec, req)
+		if rec.Code != wt.code {
+			t.Errorf("Expected status %d for %v, received: %d, %s", wt.code, wt.header, rec.Code, rec.Body)
+		}
+		if trigger := strings.Join(tr.triggered, ","); trigger != wt.trigger {
+			t.Errorf("Expected trigger %q for %v, received: %q", wt.trigger, wt.header, trigger)
+		}
+	}
+}
+
+// TestHandler_ServeHTTP_Unavailable tests the response without repository to check.
+func TestHandler_ServeHTTP_Unavailable(t *testing.T) {
+	req := httptest.NewRequest("POST", "/hooks", strings.NewReader(`{}`))
+	req.Header.Set("X-Gitlab-Event", "Push Hook")
+	req.Header.Set("X-Gitlab-Token", secretTest)
+	rec := httptest.NewRecorder()
+	h, err := webhook.New(&FakeTrigger{}, secretTest)
+	if err != nil {
+		t.Fatalf("Expected no error, received: %v", err)
+	}
+	h.ServeHTTP(rec, req)
+	if rec.Code != http.StatusServiceUnavailable {
+		t.Errorf("Expected status %d, received: %d", http.StatusServiceUnavailable, rec.Code)
+	}
+}
+
+// TestNew tests that a handler requires a secret.
+func TestNew(t *testing.T) {
+	for _, secret := range []string{"", " "} {
+		if _, err := webhook.New(&FakeTrigger{}, secret); err == nil {
+			t.Errorf("Expected error with the secret %q", secret)
+		}
+	}
+}
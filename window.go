@@ -0,0 +1,168 @@
+package gitup
+
+import (
+	"errors"
+	"strings"
+	"time"
+)
+
+// Error messages.
+const (
+	errMsgWindow   = "not a valid maintenance window"
+	errMsgDeferred = "update deferred until the next maintenance window"
+)
+
+// ErrDeferred is returned by an automatic update outside of the maintenance windows.
+var ErrDeferred = errors.New(errMsgDeferred)
+
+// Enable testing by mocking the current time.
+var timeNow = time.Now
+
+// dayNames lists the short names of the days, as used by ParseWindow.
+var dayNames = [...]string{"sun", "mon", "tue", "wed", "thu", "fri", "sat"}
+
+// Window represents a maintenance window, like on weekdays from 02:00 to 04:00 in a given time zone.
+// Without day, the window is open every day. Start and End are durations since midnight.
+// When End is before Start, the window ends the next day. When they are equals, it lasts all day.
+type Window struct {
+	Days       []time.Weekday
+	Start, End time.Duration
+	Location   *time.Location
+}
+
+// ParseWindow returns the maintenance window defined by these strings.
+// Days are a list of ranges like "mon-fri,sun", times are formatted as "15:04"
+// and the time zone is a location name like "Europe/Paris", by default UTC.
+func ParseWindow(days, start, end, timezone string) (w Window, err error) {
+	if w.Days, err = parseDays(days); err != nil {
+		return
+	}
+	if w.Start, err = parseClock(start); err != nil {
+		return
+	}
+	if w.End, err = parseClock(end); err != nil {
+		return
+	}
+	w.Location, err = time.LoadLocation(timezone)
+	return
+}
+
+// Contains returns true if the time is inside the maintenance window.
+func (w Window) Contains(t time.Time) bool {
+	if w.Location != nil {
+		t = t.In(w.Location)
+	}
+	offset := time.Duration(t.Hour())*time.Hour + time.Duration(t.Minute())*time.Minute + time.Duration(t.Second())*time.Second
+	switch {
+	case w.Start == w.End:
+		return w.hasDay(t.Weekday())
+	case w.Start < w.End:
+		return w.hasDay(t.Weekday()) && offset >= w.Start && offset < w.End
+	}
+	// The window started the day before.
+	return (w.hasDay(t.Weekday()) && offset >= w.Start) || (w.hasDay((t.Weekday()+6)%7) && offset < w.End)
+}
+
+// Next returns the next opening of the maintenance window after t.
+func (w Window) Next(t time.Time) time.Time {
+	loc := w.Location
+	if loc == nil {
+		loc = t.Location()
+	}
+	lt := t.In(loc)
+	for i := 0; i <= 7; i++ {
+		day := time.Date(lt.Year(), lt.Month(), lt.Day()+i, 0, 0, 0, 0, loc)
+		if start := day.Add(w.Start); start.After(t) && w.hasDay(day.Weekday()) {
+			return start
+		}
+	}
+	return time.Time{}
+}
+
+// hasDay returns true if the window is open this day.
+func (w Window) hasDay(day time.Weekday) bool {
+	if len(w.Days) == 0 {
+		return true
+	}
+	for _, d := range w.Days {
+		if d == day {
+			return true
+		}
+	}
+	return false
+}
+
+// AddWindow adds a maintenance window: the automatic updates are only applied inside one of them.
+// Outside, the update is deferred. The manual updates ignore them.
+func (s *UpdateStrategy) AddWindow(w Window) {
+	s.windows = append(s.windows, w)
+}
+
+// inWindow returns true if no maintenance window is defined or if one of them contains the time.
+func (s *UpdateStrategy) inWindow(t time.Time) bool {
+	if len(s.windows) == 0 {
+		return true
+	}
+	for _, w := range s.windows {
+		if w.Contains(t) {
+			return true
+		}
+	}
+	return false
+}
+
+// nextWindow returns the next opening of a maintenance window after t, a zero time without window.
+func (s *UpdateStrategy) nextWindow(t time.Time) (next time.Time) {
+	for _, w := range s.windows {
+		if n := w.Next(t); !n.IsZero() && (next.IsZero() || n.Before(next)) {
+			next = n
+		}
+	}
+	return
+}
+
+// parseDays returns the days of a list of ranges, like "mon-fri,sun".
+func parseDays(str string) (days []time.Weekday, err error) {
+	if str = strings.TrimSpace(str); str == "" {
+		return
+	}
+	for _, part := range strings.Split(str, ",") {
+		rng := strings.SplitN(part, "-", 2)
+		var low, high time.Weekday
+		if low, err = parseDay(rng[0]); err != nil {
+			return
+		}
+		if high = low; len(rng) == 2 {
+			if high, err = parseDay(rng[1]); err != nil {
+				return
+			}
+		}
+		for d := low; ; d = (d + 1) % 7 {
+			days = append(days, d)
+			if d == high {
+				break
+			}
+		}
+	}
+	return
+}
+
+// parseDay returns the day of its short name.
+func parseDay(name string) (time.Weekday, error) {
+	name = strings.ToLower(strings.TrimSpace(name))
+	for d, n := range dayNames {
+		if n == name {
+			return time.Weekday(d), nil
+		}
+	}
+	return 0, errors.New(errMsgWindow)
+}
+
+// parseClock returns the duration since midnight of a time like "02:30".
+func parseClock(str string) (time.Duration, error) {
+	t, err := time.Parse("15:04", strings.TrimSpace(str))
+	if err != nil {
+		return 0, errors.New(errMsgWindow)
+	}
+	return time.Duration(t.Hour())*time.Hour + time.Duration(t.Minute())*time.Minute, nil
+}
@@ -0,0 +1,134 @@
+package gitup
+
+import (
+	"testing"
+	"time"
+)
+
+var windowErrTests = []struct {
+	days, start, end, timezone string // input
+}{
+	{"monday", "02:00", "04:00", ""},
+	{"mon-", "02:00", "04:00", ""},
+	{"mon", "2h", "04:00", ""},
+	{"mon", "02:00", "24:00", ""},
+	{"mon", "02:00", "04:00", "Mars/Olympus"},
+}
+
+var containsTests = []struct {
+	days, start, end string    // input
+	at               time.Time // Thursday 1st June 2017
+	ok               bool      // expected result
+}{
+	{"", "02:00", "04:00", date(1, 2, 0), true},
+	{"", "02:00", "04:00", date(1, 4, 0), false},
+	{"", "02:00", "04:00", date(1, 1, 59), false},
+	{"mon-fri", "02:00", "04:00", date(3, 3, 0), false}, // Saturday
+	{"fri-mon", "02:00", "04:00", date(4, 3, 0), true},  // Sunday
+	{"fri-mon", "02:00", "04:00", date(1, 3, 0), false},
+	{"thu", "22:00", "02:00", date(1, 23, 0), true}, // ends the next day
+	{"thu", "22:00", "02:00", date(2, 1, 0), true},
+	{"thu", "22:00", "02:00", date(2, 23, 0), false},
+	{"sat,sun", "00:00", "00:00", date(3, 15, 0), true}, // all day
+	{"sat,sun", "00:00", "00:00", date(1, 15, 0), false},
+}
+
+// date returns the time in UTC for this day of June 2017.
+func date(day, hour, min int) time.Time {
+	return time.Date(2017, 6, day, hour, min, 0, 0, time.UTC)
+}
+
+// TestParseWindow tests ParseWindow method with invalid windows.
+func TestParseWindow(t *testing.T) {
+	for _, wt := range windowErrTests {
+		if _, err := ParseWindow(wt.days, wt.start, wt.end, wt.timezone); err == nil {
+			t.Errorf("Expected error with window %#v", wt)
+		}
+	}
+	w, err := ParseWindow("sat-mon", "02:30", "04:00", "Europe/Paris")
+	if err != nil {
+		t.Fatalf("Expected no error, received: %v", err)
+	}
+	if len(w.Days) != 3 || w.Days[2] != time.Monday || w.Start != 150*time.Minute || w.Location.String() != "Europe/Paris" {
+		t.Errorf("Expected parsed window, received: %#v", w)
+	}
+	// 02:30 in Paris is 00:30 in UTC during the summer.
+	if !w.Contains(date(3, 0, 30)) {
+		t.Error("Expected window in its time zone")
+	}
+}
+
+// TestWindow_Contains tests Contains method with various windows.
+func TestWindow_Contains(t *testing.T) {
+	for _, wt := range containsTests {
+		w, err := ParseWindow(wt.days, wt.start, wt.end, "")
+		if err != nil {
+			t.Fatalf("Expected no error, received: %v", err)
+		}
+		if ok := w.Contains(wt.at); ok != wt.ok {
+			t.Errorf("Expected %t for %v in %v %v-%v, received: %t", wt.ok, wt.at, wt.days, wt.start, wt.end, ok)
+		}
+	}
+}
+
+// TestWindow_Next tests the next opening of the windows.
+func TestWindow_Next(t *testing.T) {
+	s := UpdateStrategy{}
+	if next := s.nextWindow(date(1, 10, 0)); !next.IsZero() {
+		t.Errorf("Expected no opening without window, received: %v", next)
+	}
+	w1, _ := ParseWindow("mon-fri", "02:00", "04:00", "")
+	w2, _ := ParseWindow("sat", "12:00", "14:00", "")
+	s.AddWindow(w1)
+	s.AddWindow(w2)
+	for from, exp := range map[time.Time]time.Time{
+		date(1, 1, 0):  date(1, 2, 0),
+		date(1, 10, 0): date(2, 2, 0),
+		date(2, 10, 0): date(3, 12, 0),
+		date(3, 13, 0): date(5, 2, 0),
+	} {
+		if next := s.nextWindow(from); !next.Equal(exp) {
+			t.Errorf("Expected next opening %v from %v, received: %v", exp, from, next)
+		}
+	}
+}
+
+// TestRepo_Update_Window tests that an automatic update is deferred outside the windows.
+func TestRepo_Update_Window(t *testing.T) {
+	// Restore the current time at the end of the test.
+	defer func() { timeNow = time.Now }()
+
+	w, _ := ParseWindow("", "02:00", "04:00", "")
+	s := UpdateStrategy{until: [4]uint8{Auto}}
+	s.AddWindow(w)
+
+	git := &FakeStepFlow{FakeGitFlow: FakeGitFlow{localTag: "v1.0.0", remoteTag: "v1.1.0"}}
+	m := NewManager()
+	m.Add("app", &Repo{git: git}, s)
+
+	timeNow = func() time.Time { return date(1, 10, 0) }
+	if err := m.Apply("app"); err != nil {
+		t.Errorf("Expected deferred update without error, received: %v", err)
+	}
+	if st, _ := m.Status("app"); st.Deferred != "v1.1.0" || len(git.checkouts) != 0 {
+		t.Errorf("Expected deferred update, received: %#v", st)
+	}
+	if next := m.nextWindow("app", date(1, 10, 0)); !next.Equal(date(2, 2, 0)) {
+		t.Errorf("Expected next opening of the window, received: %v", next)
+	}
+	timeNow = func() time.Time { return date(2, 2, 30) }
+	if err := m.Apply("app"); err != nil || len(git.checkouts) != 1 {
+		t.Errorf("Expected update inside the window, received: %v, %v", git.checkouts, err)
+	}
+	if st, _ := m.Status("app"); st.Deferred != "" {
+		t.Errorf("Expected no more deferred update, received: %#v", st)
+	}
+	// Manual updates ignore the windows.
+	s = UpdateStrategy{until: [4]uint8{Manual}}
+	s.AddWindow(w)
+	timeNow = func() time.Time { return date(1, 10, 0) }
+	r := &Repo{git: &FakeGitFlow{localTag: "v1.0.0", remoteTag: "v1.1.0"}}
+	if err := r.Update(s); err == ErrDeferred {
+		t.Error("Expected manual update not deferred")
+	}
+}
@@ -0,0 +1,26 @@
+package gitup
+
+import (
+	"errors"
+
+	"github.com/rvflash/gitup/internal/gitflow"
+)
+
+const errMsgWorktrees = "worktrees require a Git repository"
+
+// SetWorktrees installs each version in its own Git worktree, in the directory of the versions,
+// then switches atomically the symbolic link "current" of this directory on it.
+// Keep is the number of worktrees kept on disk with the current one, all of them if zero.
+// @example SetWorktrees("/srv/app/versions", 3) => /srv/app/versions/current -> v1.4.0
+func (r *Repo) SetWorktrees(dir string, keep int) error {
+	g, ok := r.git.(*gitflow.Repo)
+	if !ok {
+		return errors.New(errMsgWorktrees)
+	}
+	w, err := gitflow.NewWorktrees(g, dir, keep)
+	if err != nil {
+		return err
+	}
+	r.SetInstaller(w)
+	return nil
+}
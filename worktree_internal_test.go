@@ -0,0 +1,82 @@
+package gitup
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// TestRepo_SetWorktrees tests the updates and rollbacks by switching of worktree.
+func TestRepo_SetWorktrees(t *testing.T) {
+	remote := newRemote(t, "v1.0.0", "v1.1.0", "v1.2.0")
+	defer os.RemoveAll(remote)
+
+	r, err := Clone("file://"+remote, filepath.Join(remote, ".clones", "app"), UpdateStrategy{}, CloneOptions{Version: "v1.0.0"})
+	if err != nil {
+		t.Fatalf("Expected no error, received: %v", err)
+	}
+	dir := filepath.Join(remote, ".clones", "versions")
+	if err = r.SetWorktrees(dir, 2); err != nil {
+		t.Fatalf("Expected no error, received: %v", err)
+	}
+	// Without worktree, the version is the one of the repository.
+	if tag, _ := r.installs().LocalTag(); tag != "v1.0.0" {
+		t.Fatalf("Expected v1.0.0 without worktree, received: %q", tag)
+	}
+	current := func(tag string) {
+		t.Helper()
+		if target, err := os.Readlink(filepath.Join(dir, "current")); err != nil || target != tag {
+			t.Errorf("Expected the link on %q, received: %q, %v", tag, target, err)
+		}
+		if _, err := os.Stat(filepath.Join(dir, "current", ".git")); err != nil {
+			t.Errorf("Expected the worktree of %q, received: %v", tag, err)
+		}
+	}
+	// Until the first worktree, the hooks run in the repository, then in the installed version.
+	if wd := r.workDir(); wd != r.path {
+		t.Errorf("Expected the hooks in %q without worktree, received: %q", r.path, wd)
+	}
+	r.AddHook(AfterUpdate, Command("touch hooked"))
+	if err = r.Update(UpdateStrategy{until: [4]uint8{Auto, Auto, Auto, Auto}}); err != nil {
+		t.Fatalf("Expected no error on update, received: %v", err)
+	}
+	current("v1.2.0")
+	if _, err = os.Stat(filepath.Join(dir, "v1.2.0", "hooked")); err != nil {
+		t.Errorf("Expected the hook run in the worktree, received: %v", err)
+	}
+	if err = r.Rollback(); err != nil {
+		t.Fatalf("Expected no error on rollback, received: %v", err)
+	}
+	current("v1.0.0")
+	// The repository itself is never checked out.
+	if tag, _ := r.git.LocalTag(); tag != "v1.0.0" {
+		t.Errorf("Expected the repository to stay on v1.0.0, received: %q", tag)
+	}
+	// Beyond the retention, the oldest worktree is removed.
+	if err = r.installs().CheckoutTag("v1.1.0"); err != nil {
+		t.Fatalf("Expected no error on checkout, received: %v", err)
+	}
+	current("v1.1.0")
+	for tag, kept := range map[string]bool{"v1.0.0": false, "v1.1.0": true, "v1.2.0": true} {
+		if _, err = os.Stat(filepath.Join(dir, tag)); kept != (err == nil) {
+			t.Errorf("Expected worktree %q to be kept: %v, received: %v", tag, kept, err)
+		}
+	}
+	for _, tag := range []string{"", "../v1.0.0", ".v1.0.0", "current", "v9.9.9"} {
+		if err = r.installs().CheckoutTag(tag); err == nil {
+			t.Errorf("Expected error with the tag %q", tag)
+		}
+	}
+	current("v1.1.0")
+}
+
+// TestRepo_SetWorktrees_NoGit tests the worktrees without Git repository.
+func TestRepo_SetWorktrees_NoGit(t *testing.T) {
+	r, err := NewRepoFrom("", FakeGitFlow{}, FakeGitFlow{})
+	if err != nil {
+		t.Fatalf("Expected no error, received: %v", err)
+	}
+	if err = r.SetWorktrees(os.TempDir(), 0); err == nil || err.Error() != errMsgWorktrees {
+		t.Errorf("Expected error %q, received: %v", errMsgWorktrees, err)
+	}
+}